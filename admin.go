@@ -0,0 +1,225 @@
+package gocachelib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// AdminOption configures admin handler
+type AdminOption func(*adminConfig)
+
+type adminConfig struct {
+	// authorizes requests to write routes, nil allows all
+	authorize func(r *http.Request) bool
+}
+
+// WithAdminAuth protects write routes of admin handler, requests not authorized get 403 Forbidden
+func WithAdminAuth(authorize func(r *http.Request) bool) AdminOption {
+	return func(c *adminConfig) {
+		c.authorize = authorize
+	}
+}
+
+// AdminHandler serves a JSON API for operating a live cache, to be mounted with http.StripPrefix:
+//
+//	GET    /keys?offset=0&limit=100  keys in order, paginated by at most 1000
+//	GET    /entry?key=...            metadata of an item
+//	DELETE /entry?key=...            delete an item
+//	POST   /refresh?key=...          refresh an item ahead of others
+//	DELETE /keys                     purge all items
+//	GET    /stats                    stats of the cache
+//
+// Keys are given as query parameters, so that keys like URLs are not cleaned as paths. Write
+// routes are protected with WithAdminAuth.
+func AdminHandler[V any](c *Cache[string, V], opts ...AdminOption) http.Handler {
+	var cfg adminConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	a := &admin[V]{cache: c, cfg: cfg}
+	router := httprouter.New()
+	router.GET("/keys", a.keys)
+	router.GET("/entry", a.inspect)
+	router.DELETE("/entry", a.write(a.delete))
+	router.POST("/refresh", a.write(a.refresh))
+	router.DELETE("/keys", a.write(a.purge))
+	router.GET("/stats", a.stats)
+	return router
+}
+
+type admin[V any] struct {
+	cache *Cache[string, V]
+	cfg   adminConfig
+}
+
+// page of keys
+type adminKeys struct {
+	Keys   []string `json:"keys"`
+	Total  int      `json:"total"`
+	Offset int      `json:"offset"`
+	Limit  int      `json:"limit"`
+}
+
+// metadata of an item
+type adminEntry struct {
+	Key           string        `json:"key"`
+	ExpireTime    time.Time     `json:"expireTime"`
+	RevokeTime    time.Time     `json:"revokeTime"`
+	Updating      bool          `json:"updating"`
+	LastError     string        `json:"lastError,omitempty"`
+	LastErrorTime *time.Time    `json:"lastErrorTime,omitempty"`
+	Failures      int           `json:"failures"`
+	RetryTime     *time.Time    `json:"retryTime,omitempty"`
+	GaveUp        bool          `json:"gaveUp"`
+	NotFound      bool          `json:"notFound"`
+	Size          int64         `json:"size"`
+	LoadDuration  time.Duration `json:"loadDuration"`
+	Hits          uint64        `json:"hits"`
+}
+
+type adminError struct {
+	Error string `json:"error"`
+}
+
+// page sizes of keys, larger limits are clamped to maxAdminLimit
+const (
+	defaultAdminLimit = 100
+	maxAdminLimit     = 1000
+)
+
+func (a *admin[V]) keys(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, adminError{err.Error()})
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultAdminLimit)
+	if err != nil || limit == 0 {
+		writeJSON(w, http.StatusBadRequest, adminError{"limit must be a positive integer"})
+		return
+	}
+	limit = minInt(limit, maxAdminLimit)
+	keys := a.cache.Keys()
+	sort.Strings(keys)
+	page := adminKeys{Keys: []string{}, Total: len(keys), Offset: offset, Limit: limit}
+	if offset < len(keys) {
+		page.Keys = keys[offset : offset+minInt(limit, len(keys)-offset)]
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (a *admin[V]) inspect(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	key, ok := keyParam(w, r)
+	if !ok {
+		return
+	}
+	info, ok := a.cache.Inspect(key)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, adminError{ErrNotCached.Error()})
+		return
+	}
+	entry := adminEntry{
+		Key:          key,
+		ExpireTime:   info.ExpireTime,
+		RevokeTime:   info.RevokeTime,
+		Updating:     info.Updating,
+		Failures:     info.Failures,
+		GaveUp:       info.GaveUp,
+		NotFound:     info.NotFound,
+		Size:         info.Size,
+		LoadDuration: info.LoadDuration,
+		Hits:         info.Hits,
+	}
+	if info.LastError != nil {
+		entry.LastError = info.LastError.Error()
+		entry.LastErrorTime = &info.LastErrorTime
+	}
+	if !info.RetryTime.IsZero() {
+		entry.RetryTime = &info.RetryTime
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (a *admin[V]) delete(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	key, ok := keyParam(w, r)
+	if !ok {
+		return
+	}
+	if !a.cache.Delete(key) {
+		writeJSON(w, http.StatusNotFound, adminError{ErrNotCached.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *admin[V]) refresh(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	key, ok := keyParam(w, r)
+	if !ok {
+		return
+	}
+	err := a.cache.Refresh(key)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusAccepted)
+	case errors.Is(err, ErrNotCached):
+		writeJSON(w, http.StatusNotFound, adminError{err.Error()})
+	case errors.Is(err, ErrNoLoader):
+		writeJSON(w, http.StatusConflict, adminError{err.Error()})
+	default:
+		writeJSON(w, http.StatusServiceUnavailable, adminError{err.Error()})
+	}
+}
+
+func (a *admin[V]) purge(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	a.cache.Purge()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *admin[V]) stats(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeJSON(w, http.StatusOK, a.cache.Stats())
+}
+
+// authorize requests to write routes
+func (a *admin[V]) write(handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if a.cfg.authorize != nil && !a.cfg.authorize(r) {
+			writeJSON(w, http.StatusForbidden, adminError{"forbidden"})
+			return
+		}
+		handle(w, r, ps)
+	}
+}
+
+// key of query parameter, responding 400 Bad Request if it is missing
+func keyParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	query := r.URL.Query()
+	if !query.Has("key") {
+		writeJSON(w, http.StatusBadRequest, adminError{"key must be given"})
+		return "", false
+	}
+	return query.Get("key"), true
+}
+
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, errors.New(name + " must be a non-negative integer")
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,175 @@
+package gocachelib
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func adminRequest(t *testing.T, h http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestAdminKeys(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	for _, key := range []string{"c", "a", "b/1"} {
+		c.AddItem(CacheItem{Key: key, Value: []byte(key)})
+	}
+	h := AdminHandler(c.Cache)
+
+	w := adminRequest(t, h, http.MethodGet, "/keys?offset=1&limit=1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var page adminKeys
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, adminKeys{Keys: []string{"b/1"}, Total: 3, Offset: 1, Limit: 1}, page)
+
+	w = adminRequest(t, h, http.MethodGet, "/keys?offset=5", nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, adminKeys{Keys: []string{}, Total: 3, Offset: 5, Limit: defaultAdminLimit}, page)
+
+	w = adminRequest(t, h, http.MethodGet, "/keys?offset=1&limit=9223372036854775807", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, []string{"b/1", "c"}, page.Keys)
+	assert.Equal(t, maxAdminLimit, page.Limit, "Limit should be clamped")
+
+	w = adminRequest(t, h, http.MethodGet, "/keys?limit=1000000000", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, adminKeys{Keys: []string{"a", "b/1", "c"}, Total: 3, Offset: 0, Limit: maxAdminLimit}, page)
+
+	w = adminRequest(t, h, http.MethodGet, "/keys?limit=-1", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAdminInspectAndDelete(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	c.AddItem(CacheItem{Key: "b/1", Value: []byte("1"), Expiration: 1 * time.Minute, TTL: 1 * time.Hour})
+	h := AdminHandler(c.Cache)
+
+	w := adminRequest(t, h, http.MethodGet, "/entry?key=b/1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var entry adminEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, "b/1", entry.Key)
+	assert.False(t, entry.ExpireTime.IsZero())
+	assert.True(t, entry.RevokeTime.After(entry.ExpireTime))
+	assert.Empty(t, entry.LastError)
+
+	w = adminRequest(t, h, http.MethodDelete, "/entry?key=b/1", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, ok := c.Get("b/1")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusNotFound, adminRequest(t, h, http.MethodGet, "/entry?key=b/1", nil).Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(t, h, http.MethodDelete, "/entry?key=b/1", nil).Code)
+}
+
+func TestAdminRefresh(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	loaded := make(chan struct{}, 1)
+	c.AddItem(CacheItem{Key: "a", Value: []byte("1"), Expiration: 1 * time.Hour, GetFunc: func(context.Context, string) ([]byte, error) {
+		loaded <- struct{}{}
+		return []byte("2"), nil
+	}})
+	c.AddItem(CacheItem{Key: "b", Value: []byte("1")})
+	h := AdminHandler(c.Cache)
+
+	assert.Equal(t, http.StatusAccepted, adminRequest(t, h, http.MethodPost, "/refresh?key=a", nil).Code)
+	select {
+	case <-loaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("item was not refreshed")
+	}
+	assert.Equal(t, http.StatusConflict, adminRequest(t, h, http.MethodPost, "/refresh?key=b", nil).Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(t, h, http.MethodPost, "/refresh?key=c", nil).Code)
+}
+
+func TestAdminPurgeAndStats(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	c.AddItem(CacheItem{Key: "a", Value: []byte("1")})
+	c.AddItem(CacheItem{Key: "b", Value: []byte("1")})
+	h := AdminHandler(c.Cache)
+
+	assert.Equal(t, http.StatusNoContent, adminRequest(t, h, http.MethodDelete, "/keys", nil).Code)
+	assert.Empty(t, c.Keys())
+
+	w := adminRequest(t, h, http.MethodGet, "/stats", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats Stats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, uint64(2), stats.Inserts)
+	assert.Equal(t, 0, stats.Entries)
+}
+
+func TestAdminAuth(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	c.AddItem(CacheItem{Key: "a", Value: []byte("1")})
+	h := AdminHandler(c.Cache, WithAdminAuth(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer secret"
+	}))
+
+	assert.Equal(t, http.StatusOK, adminRequest(t, h, http.MethodGet, "/entry?key=a", nil).Code)
+	assert.Equal(t, http.StatusForbidden, adminRequest(t, h, http.MethodDelete, "/entry?key=a", nil).Code)
+	assert.Equal(t, http.StatusForbidden, adminRequest(t, h, http.MethodDelete, "/keys", nil).Code)
+	assert.Equal(t, http.StatusForbidden, adminRequest(t, h, http.MethodPost, "/refresh?key=a", nil).Code)
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	auth := http.Header{"Authorization": {"Bearer secret"}}
+	assert.Equal(t, http.StatusNoContent, adminRequest(t, h, http.MethodDelete, "/entry?key=a", auth).Code)
+}
+
+func TestAdminURLKeys(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	key := "https://api.example.com/a?b=1"
+	c.AddItem(CacheItem{Key: key, Value: []byte("1"), GetFunc: func(context.Context, string) ([]byte, error) {
+		return []byte("2"), nil
+	}})
+	// mounted as in README
+	mux := http.NewServeMux()
+	mux.Handle("/admin/cache/", http.StripPrefix("/admin/cache", AdminHandler(c.Cache)))
+	target := "/admin/cache/entry?key=" + url.QueryEscape(key)
+
+	w := adminRequest(t, mux, http.MethodGet, target, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var entry adminEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, key, entry.Key)
+	assert.Equal(t, http.StatusAccepted, adminRequest(t, mux, http.MethodPost, "/admin/cache/refresh?key="+url.QueryEscape(key), nil).Code)
+	assert.Equal(t, http.StatusNoContent, adminRequest(t, mux, http.MethodDelete, target, nil).Code)
+	_, ok := c.Inspect(key)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(t, mux, http.MethodGet, "/admin/cache/entry", nil).Code)
+}
@@ -0,0 +1,82 @@
+package gocachelib
+
+// ARC is an adaptive replacement cache eviction policy. Items used once and those used more
+// often are kept in separate LRU lists, and keys recently evicted from either are remembered
+// as ghosts. Inserting a ghost tells which list was evicted from too eagerly, and the target
+// size of the list of items used once adapts accordingly.
+type ARC[K comparable] struct {
+	capacity int
+	// target size of t1
+	p int
+
+	// items used once and more often
+	t1, t2 *keyList[K]
+	// ghosts of keys removed from t1 and t2
+	b1, b2 *keyList[K]
+}
+
+// NewARC creates an adaptive replacement cache eviction policy for a cache of about capacity items
+func NewARC[K comparable](capacity int) *ARC[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &ARC[K]{
+		capacity: capacity,
+		t1:       newKeyList[K](),
+		t2:       newKeyList[K](),
+		b1:       newKeyList[K](),
+		b2:       newKeyList[K](),
+	}
+}
+
+func (p *ARC[K]) OnInsert(key K) {
+	switch {
+	case p.t1.contains(key) || p.t2.contains(key):
+		p.OnAccess(key)
+	case p.b1.remove(key):
+		// evicted from t1 too early, favour recency
+		p.p = minInt(p.capacity, p.p+maxInt(p.b2.len()/maxInt(p.b1.len(), 1), 1))
+		p.t2.pushFront(key)
+	case p.b2.remove(key):
+		// evicted from t2 too early, favour frequency
+		p.p = maxInt(0, p.p-maxInt(p.b1.len()/maxInt(p.b2.len(), 1), 1))
+		p.t2.pushFront(key)
+	default:
+		p.t1.pushFront(key)
+	}
+}
+
+func (p *ARC[K]) OnAccess(key K) {
+	if p.t1.remove(key) {
+		p.t2.pushFront(key)
+		return
+	}
+	p.t2.touch(key)
+}
+
+func (p *ARC[K]) OnUpdate(key K) { p.OnAccess(key) }
+
+func (p *ARC[K]) OnRemove(key K) {
+	switch {
+	case p.t1.remove(key):
+		p.b1.pushFront(key)
+	case p.t2.remove(key):
+		p.b2.pushFront(key)
+	default:
+		return
+	}
+	// ghosts are bounded to capacity per list and twice capacity in all
+	for p.t1.len()+p.b1.len() > p.capacity && p.b1.len() > 0 {
+		p.b1.popBack()
+	}
+	for p.t1.len()+p.t2.len()+p.b1.len()+p.b2.len() > 2*p.capacity && p.b2.len() > 0 {
+		p.b2.popBack()
+	}
+}
+
+func (p *ARC[K]) Victim() (K, bool) {
+	if p.t1.len() > 0 && (p.t1.len() > p.p || p.t2.len() == 0) {
+		return p.t1.back()
+	}
+	return p.t2.back()
+}
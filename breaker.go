@@ -0,0 +1,203 @@
+package gocachelib
+
+import (
+	"fmt"
+	"time"
+)
+
+// BreakerState of circuit breaker of a loader group
+type BreakerState int
+
+const (
+	// BreakerClosed refreshes run normally
+	BreakerClosed BreakerState = iota
+	// BreakerOpen refreshes are skipped and stale values served
+	BreakerOpen
+	// BreakerHalfOpen trial refreshes decide whether breaker closes or opens again
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerPolicy for circuit breakers of loader groups
+// FailureThreshold failed refreshes in a row within a group that open its breaker
+// OpenTimeout Time breaker stays open before trial refreshes are let through
+// HalfOpenMaxCalls trial refreshes let through while half-open, breaker closes when all of them succeed
+type BreakerPolicy struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenMaxCalls int
+}
+
+// BreakerStats of circuit breaker of a loader group
+// State current state of breaker
+// Failures failed refreshes in a row
+// Opens how many times breaker has opened
+// Skipped refreshes skipped while breaker was open
+// OpenedAt when breaker opened last, zero if never
+type BreakerStats struct {
+	State    BreakerState
+	Failures int
+	Opens    int
+	Skipped  int
+	OpenedAt time.Time
+}
+
+func defaultBreakerPolicy() BreakerPolicy {
+	return BreakerPolicy{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenMaxCalls: 1,
+	}
+}
+
+func (p BreakerPolicy) validate() error {
+	switch {
+	case p.FailureThreshold <= 0:
+		return fmt.Errorf("%w: breaker failure threshold must be positive, got %d", ErrInvalidConfig, p.FailureThreshold)
+	case p.OpenTimeout <= 0:
+		return fmt.Errorf("%w: breaker open timeout must be positive, got %v", ErrInvalidConfig, p.OpenTimeout)
+	case p.HalfOpenMaxCalls <= 0:
+		return fmt.Errorf("%w: breaker half-open max calls must be positive, got %d", ErrInvalidConfig, p.HalfOpenMaxCalls)
+	}
+	return nil
+}
+
+type breaker struct {
+	BreakerStats
+	// trial refreshes let through and succeeded while half-open, and when the last one was let through
+	trials    int
+	successes int
+	trialAt   time.Time
+}
+
+// state change of a breaker, reported to callback after locks are released
+type breakerChange struct {
+	group    string
+	from, to BreakerState
+}
+
+// whether a refresh may run now, opening a timed out breaker for trials
+func (b *breaker) allow(p BreakerPolicy, now time.Time) (bool, *breakerChange) {
+	var change *breakerChange
+	if b.State == BreakerOpen {
+		if now.Before(b.OpenedAt.Add(p.OpenTimeout)) {
+			b.Skipped++
+			return false, nil
+		}
+		change = b.set(BreakerHalfOpen)
+	}
+	if b.State == BreakerHalfOpen {
+		// trials whose results never came, for example because item was revoked meanwhile, are given up after open timeout
+		if b.trials >= p.HalfOpenMaxCalls && now.Before(b.trialAt.Add(p.OpenTimeout)) {
+			b.Skipped++
+			return false, change
+		}
+		if b.trials >= p.HalfOpenMaxCalls {
+			b.trials = 0
+			b.successes = 0
+		}
+		b.trials++
+		b.trialAt = now
+	}
+	return true, change
+}
+
+// record result of a refresh
+func (b *breaker) record(p BreakerPolicy, failed bool, now time.Time) *breakerChange {
+	if !failed {
+		b.Failures = 0
+		if b.State == BreakerHalfOpen {
+			b.successes++
+			if b.successes >= p.HalfOpenMaxCalls {
+				return b.set(BreakerClosed)
+			}
+		}
+		return nil
+	}
+	b.Failures++
+	if b.State == BreakerHalfOpen || (b.State == BreakerClosed && b.Failures >= p.FailureThreshold) {
+		b.OpenedAt = now
+		b.Opens++
+		return b.set(BreakerOpen)
+	}
+	return nil
+}
+
+func (b *breaker) set(state BreakerState) *breakerChange {
+	change := &breakerChange{from: b.State, to: state}
+	b.State = state
+	b.trials = 0
+	b.successes = 0
+	return change
+}
+
+// Breakers returns stats of circuit breakers by loader group
+func (c *Cache[K, V]) Breakers() map[string]BreakerStats {
+	c.breakersMutex.Lock()
+	defer c.breakersMutex.Unlock()
+	stats := make(map[string]BreakerStats, len(c.breakers))
+	for group, b := range c.breakers {
+		stats[group] = b.BreakerStats
+	}
+	return stats
+}
+
+// whether a refresh of group may run now
+func (c *Cache[K, V]) allowRefresh(group string, now time.Time) (bool, *breakerChange) {
+	if group == "" {
+		return true, nil
+	}
+	c.breakersMutex.Lock()
+	defer c.breakersMutex.Unlock()
+	b, ok := c.breakers[group]
+	if !ok {
+		b = &breaker{}
+		c.breakers[group] = b
+	}
+	allowed, change := b.allow(c.cfg.breakerPolicy, now)
+	if change != nil {
+		change.group = group
+	}
+	return allowed, change
+}
+
+// record result of a refresh of group
+func (c *Cache[K, V]) recordRefresh(group string, failed bool, now time.Time) *breakerChange {
+	if group == "" {
+		return nil
+	}
+	c.breakersMutex.Lock()
+	defer c.breakersMutex.Unlock()
+	b, ok := c.breakers[group]
+	if !ok {
+		return nil
+	}
+	change := b.record(c.cfg.breakerPolicy, failed, now)
+	if change != nil {
+		change.group = group
+	}
+	return change
+}
+
+// log and report breaker state change. The callback is run on the hook dispatcher, so that a slow
+// one does not stall refreshing and one closing the cache does not wait on itself.
+func (c *Cache[K, V]) breakerChanged(change *breakerChange) {
+	if change == nil {
+		return
+	}
+	c.cfg.logger.Warn("Circuit breaker of loader group changed state", "group", change.group, "from", change.from.String(), "to", change.to.String())
+	if f := c.cfg.onBreakerChange; f != nil {
+		c.hooks.later(func() { f(change.group, change.from, change.to) })
+	}
+}
@@ -0,0 +1,157 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakerStateMachine(t *testing.T) {
+	p := BreakerPolicy{FailureThreshold: 2, OpenTimeout: 1 * time.Second, HalfOpenMaxCalls: 2}
+	b := &breaker{}
+	now := time.Now()
+
+	allowed, change := b.allow(p, now)
+	assert.True(t, allowed)
+	assert.Nil(t, change)
+	assert.Nil(t, b.record(p, true, now))
+	change = b.record(p, true, now)
+	assert.Equal(t, &breakerChange{from: BreakerClosed, to: BreakerOpen}, change, "Breaker should open after threshold failures in a row")
+	assert.Equal(t, 1, b.Opens)
+
+	allowed, _ = b.allow(p, now.Add(500*time.Millisecond))
+	assert.False(t, allowed, "Open breaker should skip refreshes")
+	assert.Equal(t, 1, b.Skipped)
+
+	later := now.Add(1 * time.Second)
+	allowed, change = b.allow(p, later)
+	assert.True(t, allowed)
+	assert.Equal(t, &breakerChange{from: BreakerOpen, to: BreakerHalfOpen}, change, "Breaker should let trials through after open timeout")
+	allowed, _ = b.allow(p, later)
+	assert.True(t, allowed)
+	allowed, _ = b.allow(p, later)
+	assert.False(t, allowed, "Only max calls trials should be let through while half-open")
+
+	assert.Nil(t, b.record(p, false, later))
+	change = b.record(p, false, later)
+	assert.Equal(t, &breakerChange{from: BreakerHalfOpen, to: BreakerClosed}, change, "Breaker should close when trials succeed")
+	assert.Equal(t, 0, b.Failures)
+}
+
+func TestBreakerReopensOnFailedTrial(t *testing.T) {
+	p := BreakerPolicy{FailureThreshold: 1, OpenTimeout: 1 * time.Second, HalfOpenMaxCalls: 1}
+	b := &breaker{}
+	now := time.Now()
+	b.record(p, true, now)
+	later := now.Add(1 * time.Second)
+	allowed, _ := b.allow(p, later)
+	assert.True(t, allowed)
+	change := b.record(p, true, later)
+	assert.Equal(t, &breakerChange{from: BreakerHalfOpen, to: BreakerOpen}, change)
+	assert.Equal(t, later, b.OpenedAt)
+	assert.Equal(t, 2, b.Opens)
+}
+
+func TestBreakerGivesUpLostTrials(t *testing.T) {
+	p := BreakerPolicy{FailureThreshold: 1, OpenTimeout: 1 * time.Second, HalfOpenMaxCalls: 1}
+	b := &breaker{}
+	now := time.Now()
+	b.record(p, true, now)
+	allowed, _ := b.allow(p, now.Add(1*time.Second))
+	assert.True(t, allowed)
+	// result of the trial never comes
+	allowed, _ = b.allow(p, now.Add(1500*time.Millisecond))
+	assert.False(t, allowed)
+	allowed, _ = b.allow(p, now.Add(2*time.Second))
+	assert.True(t, allowed, "New trial should be let through when the previous one got lost")
+}
+
+func TestInvalidBreakerPolicy(t *testing.T) {
+	for name, p := range map[string]BreakerPolicy{
+		"zero threshold":       {OpenTimeout: 1 * time.Second, HalfOpenMaxCalls: 1},
+		"zero open timeout":    {FailureThreshold: 1, HalfOpenMaxCalls: 1},
+		"zero half-open calls": {FailureThreshold: 1, OpenTimeout: 1 * time.Second},
+	} {
+		_, err := New[string, []byte](WithCircuitBreaker(p))
+		assert.True(t, errors.Is(err, ErrInvalidConfig), name)
+	}
+}
+
+func TestOpenBreakerSkipsRefreshesOfGroup(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var changes []BreakerState
+	c := mustNew(t, WithWorkers(1), WithMaxEntries(10), WithLoopInterval(10*time.Millisecond), WithRetryPolicy(fastRetry),
+		WithCircuitBreaker(BreakerPolicy{FailureThreshold: 2, OpenTimeout: 60 * time.Millisecond, HalfOpenMaxCalls: 1}),
+		WithBreakerStateChange(func(group string, from, to BreakerState) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, "down.example.com", group)
+			changes = append(changes, to)
+		}))
+	c.Start()
+	defer c.Close(context.Background())
+	var healthy int32 = 0
+	var downCalls int32
+	failing := func(ctx context.Context, key string) ([]byte, error) {
+		atomic.AddInt32(&downCalls, 1)
+		if atomic.LoadInt32(&healthy) == 1 {
+			return []byte("recovered"), nil
+		}
+		return nil, errors.New("origin down")
+	}
+	for _, key := range []string{"TestOpenBreaker1", "TestOpenBreaker2", "TestOpenBreaker3"} {
+		c.AddItem(CacheItem{Key: key, Value: []byte("stale"), Expiration: 1 * time.Millisecond, Group: "down.example.com", GetFunc: failing})
+	}
+	c.AddItem(CacheItem{Key: "TestOpenBreakerHealthy", Value: []byte("old"), Expiration: 1 * time.Millisecond, Group: "up.example.com", GetFunc: randomGetFunc})
+	time.Sleep(40 * time.Millisecond)
+
+	stats := c.Breakers()
+	assert.Equal(t, BreakerOpen, stats["down.example.com"].State)
+	assert.True(t, stats["down.example.com"].Skipped > 0, "Refreshes should be skipped while breaker is open")
+	assert.Equal(t, BreakerClosed, stats["up.example.com"].State)
+	callsWhileOpen := atomic.LoadInt32(&downCalls)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, callsWhileOpen, atomic.LoadInt32(&downCalls), "Open breaker should stop refreshes of its group")
+	assert.Equal(t, "stale", string(c.GetValue("TestOpenBreaker1")), "Stale value should be served while breaker is open")
+	assert.NotEqual(t, "old", string(c.GetValue("TestOpenBreakerHealthy")), "Other groups should keep refreshing")
+
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, BreakerClosed, c.Breakers()["down.example.com"].State, "Successful trial should close the breaker")
+	assert.Equal(t, "recovered", string(c.GetValue("TestOpenBreaker2")))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed}, changes)
+}
+
+func TestBreakerStateChangeMayCloseCache(t *testing.T) {
+	t.Parallel()
+	closed := make(chan error, 1)
+	var c *BytesCache
+	c = mustNew(t, WithWorkers(1), WithLoopInterval(5*time.Millisecond), WithRetryPolicy(fastRetry),
+		WithCircuitBreaker(BreakerPolicy{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond, HalfOpenMaxCalls: 1}),
+		WithBreakerStateChange(func(group string, from, to BreakerState) {
+			if to == BreakerHalfOpen {
+				_, err := c.Close(context.Background())
+				closed <- err
+			}
+		}))
+	c.Start()
+	c.AddItem(CacheItem{Key: "TestBreakerStateChangeMayCloseCache", Value: []byte("stale"), Expiration: 1 * time.Millisecond, Group: "down.example.com",
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			return nil, errors.New("origin down")
+		}})
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+		assert.Equal(t, StateStopped, c.State())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Closing cache from breaker state change callback should not deadlock")
+	}
+}
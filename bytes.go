@@ -0,0 +1,154 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// cache used by the package level functions, replaced by StartWith
+var (
+	defaultCache      = &BytesCache{newCache[string, []byte](defaultConfig())}
+	defaultCacheMutex sync.RWMutex
+)
+
+func getDefaultCache() *BytesCache {
+	defaultCacheMutex.RLock()
+	defer defaultCacheMutex.RUnlock()
+	return defaultCache
+}
+
+// errNoValue tells worker to keep the old value for another expiration when GetFunc returned neither value nor error
+var errNoValue = errors.New("GetFunc returned no value")
+
+// BytesCache caches []byte values keyed by strings, for example response bodies keyed by url.
+// All Cache methods are available, AddItem and GetValue work with CacheItem and plain values.
+type BytesCache struct {
+	*Cache[string, []byte]
+}
+
+// CacheItem for cached items
+// Key cache key, for example url
+// Value to be cached
+// Expiration Time to expire item, if not set the one given by GetFunc in LoadState or cache default.
+// Item is refreshed using GetFunc after it expires
+// TTL Time to revocation from cache after last access, cache default if not set
+// GetFunc function for updating the value. On error the old value is kept and the error recorded,
+// nil value without error just keeps the old value. Context is cancelled when RefreshTimeout passes.
+// RefreshTimeout Time to wait for GetFunc when refreshing the item, cache default if not set
+// RetryPolicy for retrying failed refreshes, cache default if not set
+// Group of items sharing a circuit breaker, for example origin host. Items without group have no breaker.
+// Priority of refreshing the item, higher ones are refreshed first when workers are busy
+// MaxStale Time after expiring the value is served while being refreshed, cache default if not set
+// StaleIfError Time after expiring the value is served while refreshes fail, cache default if not set
+type CacheItem struct {
+	Key            string
+	Value          []byte
+	Expiration     time.Duration
+	TTL            time.Duration
+	GetFunc        func(ctx context.Context, key string) ([]byte, error)
+	RefreshTimeout time.Duration
+	RetryPolicy    *RetryPolicy
+	Group          string
+	Priority       int
+	MaxStale       time.Duration
+	StaleIfError   time.Duration
+}
+
+// NewBytes creates a []byte valued cache, see New
+func NewBytes(opts ...Option) (*BytesCache, error) {
+	c, err := New[string, []byte](opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &BytesCache{c}, nil
+}
+
+// AddItem sets the item to cache and updates its revoke and expire times.
+// ErrNotRunning is returned if cache has not been started or is closed.
+func (c *BytesCache) AddItem(item CacheItem) error {
+	return c.Set(item.item())
+}
+
+// GetValue value from cache, nil if not found, known to be missing or cache is not running
+func (c *BytesCache) GetValue(key string) []byte {
+	value, _ := c.Get(key)
+	return value
+}
+
+func (i CacheItem) item() Item[string, []byte] {
+	item := Item[string, []byte]{
+		Key:            i.Key,
+		Value:          i.Value,
+		Expiration:     i.Expiration,
+		TTL:            i.TTL,
+		RefreshTimeout: i.RefreshTimeout,
+		RetryPolicy:    i.RetryPolicy,
+		Group:          i.Group,
+		Priority:       i.Priority,
+		MaxStale:       i.MaxStale,
+		StaleIfError:   i.StaleIfError,
+	}
+	if i.GetFunc != nil {
+		getFunc := i.GetFunc
+		item.Loader = func(ctx context.Context, key string) ([]byte, error) {
+			value, err := getFunc(ctx, key)
+			if err == nil && value == nil {
+				return nil, errNoValue
+			}
+			return value, err
+		}
+	}
+	return item
+}
+
+// StartWith background loading default cache configured by options. The default cache can be
+// configured only before it is started, ErrAlreadyStarted or ErrClosed is returned after that.
+func StartWith(opts ...Option) error {
+	c, err := NewBytes(opts...)
+	if err != nil {
+		return err
+	}
+	defaultCacheMutex.Lock()
+	defer defaultCacheMutex.Unlock()
+	// the replaced cache must not be started meanwhile
+	if !defaultCache.transition(StateNew, StateStopped) {
+		if defaultCache.State() == StateRunning {
+			return ErrAlreadyStarted
+		}
+		return ErrClosed
+	}
+	defaultCache = c
+	return c.Start()
+}
+
+// Start background loading default cache with its current configuration
+func Start() error {
+	return getDefaultCache().Start()
+}
+
+// Close default cache, see Cache.Close
+func Close(ctx context.Context) (CloseReport[string], error) {
+	return getDefaultCache().Close(ctx)
+}
+
+// GetOrLoad value from default cache or load it, see Cache.GetOrLoad
+func GetOrLoad(ctx context.Context, key string, loader Loader[string, []byte]) ([]byte, error) {
+	return getDefaultCache().GetOrLoad(ctx, key, loader)
+}
+
+// AddItem sets the item to default cache, see BytesCache.AddItem
+func AddItem(item CacheItem) error {
+	return getDefaultCache().AddItem(item)
+}
+
+// Lookup value from default cache, see Cache.Lookup
+func Lookup(key string) ([]byte, LookupStatus) {
+	return getDefaultCache().Lookup(key)
+}
+
+// GetValue value from default cache, see BytesCache.GetValue
+func GetValue(key string) []byte {
+	return getDefaultCache().GetValue(key)
+}
@@ -0,0 +1,45 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// not parallel, the default cache is shared by the whole package and can be started only once
+func TestDefaultCache(t *testing.T) {
+	defaultCacheMutex.Lock()
+	defaultCache = &BytesCache{newCache[string, []byte](defaultConfig())}
+	defaultCacheMutex.Unlock()
+
+	assert.True(t, errors.Is(StartWith(WithWorkers(0)), ErrInvalidConfig))
+	assert.Equal(t, ErrNotRunning, AddItem(CacheItem{Key: "a", Value: []byte("1")}), "Items should not be accepted before Start")
+
+	assert.NoError(t, StartWith(WithWorkers(2), WithMaxEntries(100)))
+	assert.Equal(t, 100, getDefaultCache().cfg.maxEntries)
+	assert.Equal(t, ErrAlreadyStarted, StartWith(WithWorkers(3)), "Running default cache should not be replaced")
+	assert.Equal(t, ErrAlreadyStarted, Start())
+	assert.Equal(t, 2, getDefaultCache().cfg.workers)
+
+	assert.NoError(t, AddItem(CacheItem{Key: "a", Value: []byte("1"), Expiration: 1 * time.Minute}))
+	assert.Equal(t, "1", string(GetValue("a")))
+	value, status := Lookup("a")
+	assert.Equal(t, LookupHit, status)
+	assert.Equal(t, "1", string(value))
+	value, err := GetOrLoad(context.Background(), "b", func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("2"), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", string(value))
+	assert.Equal(t, "2", string(GetValue("b")))
+
+	_, err = Close(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, GetValue("a"))
+	assert.Equal(t, ErrNotRunning, AddItem(CacheItem{Key: "a", Value: []byte("1")}))
+	assert.Equal(t, ErrClosed, StartWith(WithWorkers(2)), "Closed default cache should not be replaced")
+	assert.Equal(t, ErrClosed, Start())
+}
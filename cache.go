@@ -1,185 +1,579 @@
 package gocachelib
 
 import (
-	"log"
+	"context"
+	"errors"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	cmap "github.com/streamrail/concurrent-map"
 )
 
-var cache cmap.ConcurrentMap
+// Cache is an in-memory loading cache of V values keyed by K, with its own worker pool and
+// refresh & revoke loops. Several independently sized caches can be used side by side.
+type Cache[K comparable, V any] struct {
+	// items and their total size in bytes guarded by mu
+	items map[K]*entry[K, V]
+	bytes int64
+	mu    sync.Mutex
+
+	// entries by refresh and revoke time, guarded by mu
+	refreshes *timeIndex[K, V]
+	revokes   *timeIndex[K, V]
+
+	sizer  Sizer[K, V]
+	policy EvictionPolicy[K]
+
+	cfg config
+
+	queue *refreshQueue[K, V]
 
-// how many simultaneus workers should we have, default 20
-var workerAmount = 20
+	// context of background refreshes, cancelled when cache is closed
+	ctx    context.Context
+	cancel context.CancelFunc
 
-// maximum amount of jobs buffered, default 200
-var bufferedJobs = 200
+	// closed to stop refresh and revoke loops
+	stopLoops chan struct{}
 
-// default cache size
-var cacheSize = 20
+	// lifecycle State, accessed atomically
+	state int32
 
-// default ttl
-var ttl = 1 * time.Hour
+	// serializes loops with Start and Close
+	loopMutex sync.Mutex
 
-// revoke & refresh loop interval
-var loopInterval = 1 * time.Second
+	workerWg sync.WaitGroup
 
-var jobs chan timedCacheItem
+	counters counters
 
-var refreshTicker *time.Ticker
-var revokeTicker *time.Ticker
+	// hooks and the events waiting for them
+	hooks hooks[K, V]
 
-var loopMutex = sync.Mutex{}
+	// workers running and refreshing, and last worker id, accessed atomically
+	workerCount int32
+	busyWorkers int32
+	workerIDs   int32
 
-var workerWg = sync.WaitGroup{}
+	// keys being refreshed by workers
+	inFlight      map[K]struct{}
+	inFlightMutex sync.Mutex
 
-// StartWith background loading cache with specified parameters
-func StartWith(workers, bufferSize, cacheSizeAmount int, defaultTTL time.Duration) {
-	workerAmount = workers
-	bufferedJobs = bufferSize
-	cacheSize = cacheSizeAmount
-	ttl = defaultTTL
-	Start()
+	// loads of GetOrLoad in progress
+	calls      map[K]*call[V]
+	callsMutex sync.Mutex
+
+	// circuit breakers by loader group
+	breakers      map[string]*breaker
+	breakersMutex sync.Mutex
 }
 
-// Start background loading cache with default parameters
-func Start() {
-	log.Printf("Starting in-memory cache with %d workers, %d job queue size, %d cache maximum and %d default TTL", workerAmount, bufferedJobs, cacheSize, ttl)
-	cache = cmap.New()
-	jobs = make(chan timedCacheItem, bufferedJobs)
-	// workers
-	for w := 1; w <= workerAmount; w++ {
-		go worker(w, jobs)
+// Loader fetches a fresh value for key. Returned error means the value could not be fetched.
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// Item for cached items
+// Key cache key, for example url
+// Value to be cached
+// Expiration Time to expire item, if not set the one given by Loader in LoadState or cache default.
+// Item is refreshed using Loader after it expires
+// TTL Time to revocation from cache after last access, cache default if not set
+// Loader function for updating the value, items without one are never refreshed
+// RefreshTimeout Time to wait for Loader when refreshing the item, cache default if not set
+// RetryPolicy for retrying failed refreshes, cache default if not set
+// Group of items sharing a circuit breaker, for example origin host. Items without group have no breaker.
+// Priority of refreshing the item, higher ones are refreshed first when workers are busy
+// MaxStale Time after expiring the value is served while being refreshed, cache default if not set
+// StaleIfError Time after expiring the value is served while refreshes fail, cache default if not set
+type Item[K comparable, V any] struct {
+	Key            K
+	Value          V
+	Expiration     time.Duration
+	TTL            time.Duration
+	Loader         Loader[K, V]
+	RefreshTimeout time.Duration
+	RetryPolicy    *RetryPolicy
+	Group          string
+	Priority       int
+	MaxStale       time.Duration
+	StaleIfError   time.Duration
+}
+
+type entry[K comparable, V any] struct {
+	Item[K, V]
+	RevokeTime    time.Time
+	ExpireTime    time.Time
+	Updating      bool
+	LastError     error
+	LastErrorTime time.Time
+	Failures      int
+	RetryTime     time.Time
+	GaveUp        bool
+	NotFound      bool
+	Size          int64
+
+	// how long the last successful refresh took
+	LoadDuration time.Duration
+
+	// accesses lately, halved on every successful refresh
+	Hits uint64
+
+	// what loader knows of the value, and whether expiration is left to it
+	loadState        LoadState
+	originExpiration bool
+
+	// how much earlier than expire time item is refreshed this time
+	earlyBy time.Duration
+
+	// positions in refresh and revoke indexes
+	refreshPos int
+	revokePos  int
+}
+
+// EntryInfo describes the state of a cached item
+// ExpireTime when item is refreshed next
+// RevokeTime when item is revoked unless accessed before
+// Updating whether item is being refreshed
+// LastError of the latest failed refresh, kept after later successful ones
+// LastErrorTime when the latest refresh failed
+// Failures how many refreshes in a row have failed, zero after a successful one
+// RetryTime when failed refresh is retried, zero if not backing off
+// GaveUp whether refreshing was given up after RetryPolicy.MaxAttempts failures in a row
+// NotFound whether item is a tombstone of a key known to be missing
+// Size bytes item takes of the cache budget
+// LoadDuration how long the last successful refresh took
+// Hits how often item has been accessed lately, halved on every successful refresh
+type EntryInfo struct {
+	ExpireTime    time.Time
+	RevokeTime    time.Time
+	Updating      bool
+	LastError     error
+	LastErrorTime time.Time
+	Failures      int
+	RetryTime     time.Time
+	GaveUp        bool
+	NotFound      bool
+	Size          int64
+	LoadDuration  time.Duration
+	Hits          uint64
+}
+
+// New creates a cache configured by options, falling back to defaults for those not given.
+// Invalid configuration is reported as an error wrapping ErrInvalidConfig.
+// Background loading begins when Start is called.
+func New[K comparable, V any](opts ...Option) (*Cache[K, V], error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	// byte budget replaces the default entry limit
+	if cfg.maxBytes > 0 && !cfg.maxEntriesSet {
+		cfg.maxEntries = math.MaxInt
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := sizerOf[K, V](cfg); err != nil {
+		return nil, err
+	}
+	if _, err := policyOf[K](cfg); err != nil {
+		return nil, err
+	}
+	return newCache[K, V](cfg), nil
+}
+
+func newCache[K comparable, V any](cfg config) *Cache[K, V] {
+	sizer, _ := sizerOf[K, V](cfg)
+	policy, _ := policyOf[K](cfg)
+	c := &Cache[K, V]{
+		items:     map[K]*entry[K, V]{},
+		refreshes: newRefreshIndex[K, V](cfg.refreshLeadTime),
+		revokes:   newRevokeIndex[K, V](),
+		sizer:     sizer,
+		policy:    policy,
+		cfg:       cfg,
+		inFlight:  map[K]struct{}{},
+		calls:     map[K]*call[V]{},
+		breakers:  map[string]*breaker{},
 	}
-	refreshTicker = doEvery(loopInterval, refresh)
-	revokeTicker = doEvery(loopInterval, revoke)
+	c.queue = newRefreshQueue[K, V](cfg.queueSize, cfg.queueOverflow, &c.counters.droppedRefreshes)
+	c.hooks.size = cfg.hookBuffer
+	c.hooks.dropped = &c.counters.droppedEvents
+	return c
 }
 
-// Stop background tickers, close job channel, wait for workers to finish and empty cache
-func stop() {
-	log.Printf("Stop in-memory cache background processing")
-	refreshTicker.Stop()
-	revokeTicker.Stop()
-	loopMutex.Lock()
-	defer loopMutex.Unlock()
-	close(jobs)
-	workerWg.Wait()
-	cache = cmap.New()
+// Start background loading cache. A cache can be started only once.
+func (c *Cache[K, V]) Start() error {
+	c.loopMutex.Lock()
+	defer c.loopMutex.Unlock()
+	if !c.transition(StateNew, StateRunning) {
+		if c.State() == StateRunning {
+			return ErrAlreadyStarted
+		}
+		return ErrClosed
+	}
+	c.cfg.logger.Info("Starting in-memory cache", "workers", c.cfg.workers, "queue_size", c.cfg.queueSize, "max_entries", c.cfg.maxEntries, "default_ttl", c.cfg.defaultTTL)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	// workers
+	for w := 1; w <= c.cfg.workers; w++ {
+		c.startWorker()
+	}
+	c.stopLoops = make(chan struct{})
+	doEvery(c.cfg.loopInterval, c.stopLoops, c.refresh)
+	doEvery(c.cfg.loopInterval, c.stopLoops, c.revoke)
+	return nil
 }
 
 // check and update expiring items
-func refresh() {
-	loopMutex.Lock()
-	defer loopMutex.Unlock()
+func (c *Cache[K, V]) refresh() {
+	c.loopMutex.Lock()
+	defer c.loopMutex.Unlock()
+	if c.State() != StateRunning {
+		return
+	}
 	now := time.Now()
-	for _, value := range cache.Items() {
-		item := value.(timedCacheItem)
-		if now.After(item.ExpireTime.Add(-300*time.Millisecond)) && !item.Updating {
-			item.Updating = true
-			cache.Set(item.Key, item)
-			jobs <- item
+	var due []*entry[K, V]
+	var ranks []refreshRank
+	var changes []*breakerChange
+	c.mu.Lock()
+	var skipped []*entry[K, V]
+	for e := c.refreshes.peek(); e != nil && !e.refreshAt(c.cfg.refreshLeadTime).After(now); e = c.refreshes.peek() {
+		c.refreshes.pop()
+		// stale value is served while breaker of the group is open
+		allowed, change := c.allowRefresh(e.Group, now)
+		if change != nil {
+			changes = append(changes, change)
+		}
+		if !allowed {
+			skipped = append(skipped, e)
+			continue
+		}
+		e.Updating = true
+		due = append(due, e)
+		ranks = append(ranks, e.rank(now))
+	}
+	// skipped ones stay due and are checked again next time
+	for _, e := range skipped {
+		c.refreshes.fix(e)
+	}
+	c.mu.Unlock()
+	for _, change := range changes {
+		c.breakerChanged(change)
+	}
+	var rejected []*entry[K, V]
+	for i, e := range due {
+		rejected = append(rejected, c.queue.push(e, ranks[i])...)
+	}
+	c.scaleUp()
+	if len(rejected) == 0 {
+		return
+	}
+	// dropped refreshes are tried again when due next time
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range rejected {
+		e.Updating = false
+		if c.items[e.Key] == e {
+			c.schedule(e)
 		}
 	}
 }
 
 // revoke those exceeding their TTL
-func revoke() {
-	loopMutex.Lock()
-	defer loopMutex.Unlock()
+func (c *Cache[K, V]) revoke() {
+	c.loopMutex.Lock()
+	defer c.loopMutex.Unlock()
+	if c.State() != StateRunning {
+		return
+	}
 	now := time.Now()
-	for _, value := range cache.Items() {
-		item := value.(timedCacheItem)
-		if now.After(item.RevokeTime) {
-			log.Printf("Revoking item that has not been used in %v: %v", item.TTL, item.Key)
-			cache.Remove(item.Key)
-		}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.revokes.peek(); e != nil && now.After(e.RevokeTime); e = c.revokes.peek() {
+		c.cfg.logger.Debug("Revoking cache item", "key", e.Key, "reason", "unused", "duration", e.TTL)
+		c.remove(e)
+		c.counters.revocations.Add(1)
+		c.emit(hookRevoke, eventOf[K, V](e.Key, ReasonUnused, e, nil))
 	}
 }
 
-// listen to jobs channel and handle incoming items
-func worker(id int, jobs <-chan timedCacheItem) {
-	workerWg.Add(1)
-	defer workerWg.Done()
-	for item := range jobs {
-		value := item.GetFunc(item.Key)
-		if value != nil {
-			item.Value = value
-			item.UpdateExpireTime()
+// take items from refresh queue and refresh them until queue is closed, or with autoscaling
+// until idle long enough
+func (c *Cache[K, V]) worker(id int) {
+	defer c.workerWg.Done()
+	var idleTimeout time.Duration
+	if c.cfg.scaling != nil {
+		idleTimeout = c.cfg.scaling.IdleTimeout
+	}
+	for {
+		e, ok, idle := c.queue.pop(idleTimeout)
+		if idle && c.retireWorker() {
+			c.cfg.logger.Debug("Stopping worker", "worker", id, "reason", "idle", "duration", idleTimeout)
+			return
+		}
+		if idle {
+			continue
 		}
-		item.Updating = false
-		cache.Set(item.Key, item)
+		if !ok {
+			atomic.AddInt32(&c.workerCount, -1)
+			return
+		}
+		atomic.AddInt32(&c.busyWorkers, 1)
+		c.setInFlight(e.Key, true)
+		c.mu.Lock()
+		state := e.loadState
+		c.mu.Unlock()
+		start := time.Now()
+		value, err := c.load(e, &state)
+		took := time.Since(start)
+		c.setInFlight(e.Key, false)
+		atomic.AddInt32(&c.busyWorkers, -1)
+		c.counters.recordLoad(took)
+		// origin telling the key is missing is not a failure of the origin
+		failed := err != nil && !errors.Is(err, errNoValue) && !errors.Is(err, ErrNotModified) && !errors.Is(err, ErrNotFound)
+		c.breakerChanged(c.recordRefresh(e.Group, failed, time.Now()))
+		if failed {
+			c.counters.refreshFailures.Add(1)
+		} else {
+			c.counters.refreshes.Add(1)
+		}
+		c.mu.Lock()
+		// hooks are told only of items still cached
+		cached := c.items[e.Key] == e
+		before := *e
+		switch {
+		case err == nil:
+			e.Value = value
+			e.Failures = 0
+			e.RetryTime = time.Time{}
+			e.GaveUp = false
+			e.LoadDuration = took
+			e.Hits /= 2
+			c.setLoadState(e, state)
+			c.updateExpireTime(e)
+			if c.items[e.Key] == e {
+				// size of the new value may differ, account for it and make room if needed
+				if err := c.insert(e); err != nil {
+					c.cfg.logger.Warn("Removing cache item", "key", e.Key, "reason", "too large", "error", err)
+					c.remove(e)
+					c.emit(hookEvict, eventOf(e.Key, ReasonTooLarge, &before, nil))
+					cached = false
+				}
+			}
+			if cached {
+				c.emit(hookRefresh, eventOf(e.Key, ReasonLoaded, &before, e))
+			}
+		case errors.Is(err, ErrNotModified):
+			// value is still current, keep it for another expiration
+			e.Failures = 0
+			e.RetryTime = time.Time{}
+			e.GaveUp = false
+			e.LoadDuration = took
+			e.Hits /= 2
+			c.setLoadState(e, state)
+			c.updateExpireTime(e)
+			if cached {
+				c.emit(hookRefresh, eventOf(e.Key, ReasonNotModified, &before, e))
+			}
+		case errors.Is(err, errNoValue):
+			// nothing new, keep the old value for another expiration
+			e.Failures = 0
+			e.RetryTime = time.Time{}
+			e.GaveUp = false
+			e.LoadDuration = took
+			e.Hits /= 2
+			c.updateExpireTime(e)
+			if cached {
+				c.emit(hookRefresh, eventOf(e.Key, ReasonUnchanged, &before, e))
+			}
+		case errors.Is(err, ErrNotFound):
+			c.cfg.logger.Info("Cache item no longer exists, keeping it as missing", "key", e.Key, "reason", "not found", "duration", c.cfg.negativeTTL)
+			if cached {
+				tombstone := c.tombstone(e.Key)
+				c.insert(tombstone)
+				c.emit(hookRefresh, eventOf(e.Key, ReasonNotFound, &before, tombstone))
+			}
+		default:
+			// keep the old value if fetching a new one failed and back off before retrying
+			now := time.Now()
+			e.LastError = err
+			e.LastErrorTime = now
+			e.Failures++
+			policy := c.retryPolicy(e)
+			if policy.exhausted(e.Failures) {
+				e.GaveUp = true
+				e.RetryTime = time.Time{}
+				c.cfg.logger.Error("Refreshing cache item failed, giving up and keeping old value", "key", e.Key, "failures", e.Failures, "duration", took, "error", err)
+			} else {
+				e.RetryTime = now.Add(policy.backoff(e.Failures))
+				c.cfg.logger.Warn("Refreshing cache item failed, keeping old value", "key", e.Key, "failures", e.Failures, "retry_at", e.RetryTime, "duration", took, "error", err)
+			}
+			if cached {
+				event := eventOf(e.Key, ReasonRetry, &before, e)
+				if e.GaveUp {
+					event.Reason = ReasonGaveUp
+				}
+				event.Err = err
+				c.emit(hookRefreshError, event)
+			}
+		}
+		e.Updating = false
+		if c.items[e.Key] == e {
+			c.schedule(e)
+		}
+		c.mu.Unlock()
 	}
 }
 
-// GetValue value from cache
-func GetValue(key string) []byte {
-	value, ok := cache.Get(key)
-	if ok {
-		item := value.(timedCacheItem)
-		item.UpdateRevokeTime()
-		item.Updating = false
-		cache.Set(item.Key, item)
-		return value.(timedCacheItem).Value
+// call loader of the item with its load state, giving up when refresh timeout passes or cache is closed.
+// A loader not respecting its context is left running on its own so that it does not hold the worker.
+func (c *Cache[K, V]) load(e *entry[K, V], state *LoadState) (V, error) {
+	timeout := e.RefreshTimeout
+	if timeout == 0 {
+		timeout = c.cfg.refreshTimeout
+	}
+	ctx, cancel := context.WithTimeout(withLoadState(c.ctx, state), timeout)
+	defer cancel()
+	type result struct {
+		value V
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := e.Loader(ctx, e.Key)
+		done <- result{value, err}
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
 	}
-	return nil
 }
 
-// AddItem sets the item to cache and updates its revoke and expire times
-func AddItem(item CacheItem) {
-	i := timedCacheItem{CacheItem: item}
-	i.UpdateRevokeTime()
-	i.UpdateExpireTime()
-	if cache.Count() >= cacheSize {
-		log.Print("Cache full")
-		revokeLeastViable()
+func (c *Cache[K, V]) retryPolicy(e *entry[K, V]) RetryPolicy {
+	if e.RetryPolicy != nil {
+		return *e.RetryPolicy
 	}
-	cache.Set(i.Key, i)
+	return c.cfg.retryPolicy
 }
 
-// CacheItem for cached items
-// Key cache key, for example url
-// Value to be cached
-// Expiration Time to expire item. Item is refreshed using GetFunc after it expires
-// TTL Time to revocation from cache after last access
-// GetFunc function for updating the value
-type CacheItem struct {
-	Key        string
-	Value      []byte
-	Expiration time.Duration
-	TTL        time.Duration
-	GetFunc    func(key string) []byte
+func (c *Cache[K, V]) setInFlight(key K, inFlight bool) {
+	c.inFlightMutex.Lock()
+	defer c.inFlightMutex.Unlock()
+	if inFlight {
+		c.inFlight[key] = struct{}{}
+	} else {
+		delete(c.inFlight, key)
+	}
 }
 
-type timedCacheItem struct {
-	CacheItem
-	RevokeTime time.Time
-	ExpireTime time.Time
-	Updating   bool
+// Get value from cache, fresh or stale, and postpone its revocation. False is returned if the key
+// is not found, is known to be missing or cache is not running. Lookup tells if the value is stale.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	value, status := c.Lookup(key)
+	return value, status != LookupMiss && status != LookupNotFound
 }
 
-func (i *timedCacheItem) UpdateRevokeTime() {
+// Inspect state of the item for key without postponing its revocation
+func (c *Cache[K, V]) Inspect(key K) (EntryInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return EntryInfo{}, false
+	}
+	return e.info(), true
+}
+
+// Set the item to cache and update its revoke and expire times.
+// ErrNotRunning is returned if cache has not been started or is closed, and an error wrapping
+// ErrInvalidConfig if the retry policy of the item is invalid. Items larger than the whole
+// byte budget are rejected with ErrItemTooLarge.
+func (c *Cache[K, V]) Set(item Item[K, V]) error {
+	return c.set(item, LoadState{})
+}
+
+// set item loaded with state
+func (c *Cache[K, V]) set(item Item[K, V], state LoadState) error {
+	if c.State() != StateRunning {
+		return ErrNotRunning
+	}
+	if item.RetryPolicy != nil {
+		if err := item.RetryPolicy.validate(); err != nil {
+			return err
+		}
+	}
+	e := &entry[K, V]{Item: item, originExpiration: item.Expiration == 0}
+	c.setLoadState(e, state)
+	e.UpdateRevokeTime(c.cfg.defaultTTL)
+	c.updateExpireTime(e)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.insert(e)
+}
+
+func (e *entry[K, V]) info() EntryInfo {
+	return EntryInfo{
+		ExpireTime:    e.ExpireTime,
+		RevokeTime:    e.RevokeTime,
+		Updating:      e.Updating,
+		LastError:     e.LastError,
+		LastErrorTime: e.LastErrorTime,
+		Failures:      e.Failures,
+		RetryTime:     e.RetryTime,
+		GaveUp:        e.GaveUp,
+		NotFound:      e.NotFound,
+		Size:          e.Size,
+		LoadDuration:  e.LoadDuration,
+		Hits:          e.Hits,
+	}
+}
+
+func (e *entry[K, V]) UpdateRevokeTime(defaultTTL time.Duration) {
 	now := time.Now()
-	if i.TTL == 0 {
-		i.TTL = ttl
+	if e.TTL == 0 {
+		e.TTL = defaultTTL
 	}
-	i.RevokeTime = now.Add(max(i.TTL, i.Expiration))
+	e.RevokeTime = now.Add(max(e.TTL, e.Expiration))
 }
 
-func (i *timedCacheItem) UpdateExpireTime() {
+// UpdateExpireTime to expiration from now, randomly shortened or lengthened by up to jitterFraction of it
+func (e *entry[K, V]) UpdateExpireTime(jitterFraction float64) {
 	now := time.Now()
-	i.ExpireTime = now.Add(i.Expiration)
+	e.ExpireTime = now.Add(jitter(e.Expiration, jitterFraction))
+}
+
+// update expire time with configured jitter, and draw how much earlier than that the item is
+// refreshed. Like XFetch, the earlier the longer the last load took, but drawn once per expiration
+// so that refresh loop can find due items by time.
+func (c *Cache[K, V]) updateExpireTime(e *entry[K, V]) {
+	e.UpdateExpireTime(c.cfg.expirationJitter)
+	e.earlyBy = 0
+	if c.cfg.earlyRefreshBeta > 0 && e.LoadDuration > 0 {
+		e.earlyBy = min(time.Duration(float64(e.LoadDuration)*c.cfg.earlyRefreshBeta*expRandom()), e.Expiration)
+	}
 }
 
-func revokeLeastViable() {
-	loopMutex.Lock()
-	defer loopMutex.Unlock()
-	var earliest timedCacheItem
-	for _, v := range cache.Items() {
-		if (v.(timedCacheItem).RevokeTime.Before(earliest.RevokeTime) || earliest.RevokeTime == time.Time{}) {
-			earliest = v.(timedCacheItem)
+// remove the item chosen by eviction policy, or the one with earliest revoke time if cache has no
+// policy. False is returned if there was nothing to remove. mu must be held.
+func (c *Cache[K, V]) revokeLeastViable() bool {
+	var victim *entry[K, V]
+	if c.policy != nil {
+		for victim == nil {
+			key, ok := c.policy.Victim()
+			if !ok {
+				return false
+			}
+			if victim = c.items[key]; victim == nil {
+				// policy out of sync with items, forget the key
+				c.policy.OnRemove(key)
+			}
 		}
+	} else if victim = c.revokes.peek(); victim == nil {
+		return false
 	}
-	log.Printf("Removing cache item %s with earliest revoke time to make room", earliest.Key)
-	cache.Remove(earliest.Key)
+	c.cfg.logger.Debug("Removing cache item", "key", victim.Key, "reason", "make room")
+	c.remove(victim)
+	c.counters.evictions.Add(1)
+	c.emit(hookEvict, eventOf[K, V](victim.Key, ReasonFull, victim, nil))
+	return true
 }
@@ -1,6 +1,12 @@
 package gocachelib
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,14 +15,11 @@ import (
 )
 
 func TestRevoke(t *testing.T) {
+	t.Parallel()
 	// Run refresh & revoke loops quicker than usual
-	defaultLoopInterval := loopInterval
-	defer func() {
-		stop()
-		loopInterval = defaultLoopInterval
-	}()
-	loopInterval = 10 * time.Millisecond
-	StartWith(1, 1, 1, 15*time.Millisecond)
+	c := mustNew(t, WithWorkers(1), WithQueueSize(1), WithMaxEntries(1), WithDefaultTTL(15*time.Millisecond), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
 	key := "TestRevoke"
 	i := CacheItem{
 		Key:        key,
@@ -24,21 +27,18 @@ func TestRevoke(t *testing.T) {
 		Expiration: 10 * time.Millisecond,
 		GetFunc:    noopGetFunc,
 	}
-	AddItem(i)
-	assert.True(t, string(GetValue(key)) == "TestRevoke")
+	c.AddItem(i)
+	assert.True(t, string(c.GetValue(key)) == "TestRevoke")
 	time.Sleep(25 * time.Millisecond)
-	assert.True(t, nil == GetValue(key), "Item should have been revoked by now")
+	assert.True(t, nil == c.GetValue(key), "Item should have been revoked by now")
 }
 
 func TestTTLLessThanExpiration(t *testing.T) {
+	t.Parallel()
 	// Run refresh & revoke loops quicker than usual
-	defaultLoopInterval := loopInterval
-	defer func() {
-		stop()
-		loopInterval = defaultLoopInterval
-	}()
-	loopInterval = 10 * time.Millisecond
-	StartWith(1, 1, 1, 10*time.Millisecond)
+	c := mustNew(t, WithWorkers(1), WithQueueSize(1), WithMaxEntries(1), WithDefaultTTL(10*time.Millisecond), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
 	key := "TestTTLLessThanExpiration"
 	i := CacheItem{
 		Key:        key,
@@ -47,14 +47,16 @@ func TestTTLLessThanExpiration(t *testing.T) {
 		Expiration: 20 * time.Millisecond,
 		GetFunc:    noopGetFunc,
 	}
-	AddItem(i)
+	c.AddItem(i)
 	time.Sleep(15 * time.Millisecond)
-	assert.True(t, string(GetValue(key)) == "TestTTLLessThanExpiration", "Item should still be in cache after the first revocation loop was run")
+	assert.True(t, string(c.GetValue(key)) == "TestTTLLessThanExpiration", "Item should still be in cache after the first revocation loop was run")
 }
 
 func TestGetValuePostponesRevoke(t *testing.T) {
-	StartWith(1, 1, 1, 1*time.Second)
-	defer stop()
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithQueueSize(1), WithMaxEntries(1), WithDefaultTTL(1*time.Second))
+	c.Start()
+	defer c.Close(context.Background())
 	key := "TestGetValuePostponesRevoke"
 	i := CacheItem{
 		Key:        key,
@@ -64,63 +66,62 @@ func TestGetValuePostponesRevoke(t *testing.T) {
 		GetFunc:    noopGetFunc,
 	}
 	now := time.Now()
-	AddItem(i)
-	item, ok := cache.Get(key)
+	c.AddItem(i)
+	item, ok := peek(c.Cache, key)
 	if !ok {
 		t.Errorf("Should have got item %s from cache", key)
 	}
-	revokeAfterAdd := item.(timedCacheItem).RevokeTime
+	revokeAfterAdd := item.RevokeTime
 	assert.True(t, now.Before(revokeAfterAdd))
-	assert.True(t, string(GetValue(key)) == "TestGetValuePostponesRevoke", "Item should be in cache")
+	assert.True(t, string(c.GetValue(key)) == "TestGetValuePostponesRevoke", "Item should be in cache")
 	time.Sleep(5 * time.Millisecond)
-	item, ok = cache.Get(key)
+	item, ok = peek(c.Cache, key)
 	if !ok {
 		t.Errorf("Should have got item %s from cache after first GetValue", key)
 	}
-	revokeAfterGet := item.(timedCacheItem).RevokeTime
+	revokeAfterGet := item.RevokeTime
 	assert.True(t, revokeAfterAdd.Before(revokeAfterGet), "Revoke time should be postponed after GetValue: %v < %v", revokeAfterAdd, revokeAfterGet)
 }
 
 func TestExpire(t *testing.T) {
+	t.Parallel()
 	// Run refresh & revoke loops quicker than usual
-	defaultLoopInterval := loopInterval
-	defer func() {
-		stop()
-		loopInterval = defaultLoopInterval
-	}()
-	loopInterval = 10 * time.Millisecond
-	StartWith(1, 11, 1, 1*time.Second)
+	c := mustNew(t, WithWorkers(1), WithQueueSize(11), WithMaxEntries(1), WithDefaultTTL(1*time.Second), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
 	key := "TestExpire"
-	value := randomGetFunc("")
+	value, _ := randomGetFunc(context.Background(), "")
 	i := CacheItem{
 		Key:        key,
 		Value:      value,
 		Expiration: 10 * time.Millisecond,
 		GetFunc:    randomGetFunc,
 	}
-	AddItem(i)
+	c.AddItem(i)
 	time.Sleep(20 * time.Millisecond)
-	assert.NotEqual(t, string(value), string(GetValue(key)), "Item should have new value in cache")
+	assert.NotEqual(t, string(value), string(c.GetValue(key)), "Item should have new value in cache")
 }
 
 func TestItemWithShortestTTLIsRevokedWhenCacheFillsUp(t *testing.T) {
-	StartWith(1, 11, 2, 1*time.Second)
-	defer stop()
-	AddItem(CacheItem{
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithQueueSize(11), WithMaxEntries(2), WithDefaultTTL(1*time.Second))
+	c.Start()
+	defer c.Close(context.Background())
+	c.AddItem(CacheItem{
 		Key:        "TestItemWithShortestTTLIsRevokedWhenCacheFillsUp1",
 		Value:      []byte("TestItemWithShortestTTLIsRevokedWhenCacheFillsUp1"),
 		Expiration: 1 * time.Second,
 		TTL:        2 * time.Second,
 		GetFunc:    noopGetFunc,
 	})
-	AddItem(CacheItem{
+	c.AddItem(CacheItem{
 		Key:        "TestItemWithShortestTTLIsRevokedWhenCacheFillsUp2",
 		Value:      []byte("TestItemWithShortestTTLIsRevokedWhenCacheFillsUp2"),
 		Expiration: 1 * time.Second,
 		TTL:        1 * time.Second,
 		GetFunc:    noopGetFunc,
 	})
-	AddItem(CacheItem{
+	c.AddItem(CacheItem{
 		Key:        "TestItemWithShortestTTLIsRevokedWhenCacheFillsUp3",
 		Value:      []byte("TestItemWithShortestTTLIsRevokedWhenCacheFillsUp3"),
 		Expiration: 1 * time.Second,
@@ -128,84 +129,292 @@ func TestItemWithShortestTTLIsRevokedWhenCacheFillsUp(t *testing.T) {
 		GetFunc:    noopGetFunc,
 	})
 
-	assert.Equal(t, "TestItemWithShortestTTLIsRevokedWhenCacheFillsUp1", string(GetValue("TestItemWithShortestTTLIsRevokedWhenCacheFillsUp1")))
-	assert.Equal(t, "TestItemWithShortestTTLIsRevokedWhenCacheFillsUp3", string(GetValue("TestItemWithShortestTTLIsRevokedWhenCacheFillsUp3")))
-	for k, _ := range cache.Items() {
-		assert.NotEqual(t, "TestItemWithShortestTTLIsRevokedWhenCacheFillsUp2", k, "Item #2 should have been revoked when cache was full")
-	}
+	assert.Equal(t, "TestItemWithShortestTTLIsRevokedWhenCacheFillsUp1", string(c.GetValue("TestItemWithShortestTTLIsRevokedWhenCacheFillsUp1")))
+	assert.Equal(t, "TestItemWithShortestTTLIsRevokedWhenCacheFillsUp3", string(c.GetValue("TestItemWithShortestTTLIsRevokedWhenCacheFillsUp3")))
+	_, ok := peek(c.Cache, "TestItemWithShortestTTLIsRevokedWhenCacheFillsUp2")
+	assert.False(t, ok, "Item #2 should have been revoked when cache was full")
 }
 
 func TestConcurrentRefreshAndGetValueBug(t *testing.T) {
-	// Run refresh & revoke loops quicker than usual
-	defaultLoopInterval := loopInterval
-	defer func() {
-		stop()
-		loopInterval = defaultLoopInterval
-	}()
-	loopInterval = 10 * time.Millisecond
-	// Make sure revoke does not interfere here
-	StartWith(1, 11, 1, 5*time.Second)
+	t.Parallel()
+	// Run refresh & revoke loops quicker than usual, but make sure revoke does not interfere here
+	c := mustNew(t, WithWorkers(1), WithQueueSize(11), WithMaxEntries(1), WithDefaultTTL(5*time.Second), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
 	// continuously spamming GetValue should manifest the bug
-	c := make(chan []byte, 1)
+	done := make(chan []byte, 1)
 	key := "TestConcurrentRefreshAndGetValueBug"
-	go busyGet(t, c, key)
-	value := randomGetFunc("")
+	go busyGet(c, done, key)
+	value, _ := randomGetFunc(context.Background(), "")
 	i := CacheItem{
 		Key:        key,
 		Value:      value,
 		Expiration: 10 * time.Millisecond,
 		GetFunc:    randomGetFunc,
 	}
-	AddItem(i)
+	c.AddItem(i)
 	// sleep long enough for the bug to kick in repeatably
 	time.Sleep(1013 * time.Millisecond)
-	c <- []byte("stop")
-	v, _ := cache.Get(key)
-	ci := v.(timedCacheItem)
-	assert.NotEqual(t, ci.Updating, true, "Item Should not be in updating state")
+	done <- []byte("stop")
+	// item may be refreshed at this very moment, but should not stay in updating state
+	updating := true
+	for deadline := time.Now().Add(50 * time.Millisecond); updating && time.Now().Before(deadline); time.Sleep(1 * time.Millisecond) {
+		ci, _ := peek(c.Cache, key)
+		updating = ci.Updating
+	}
+	assert.NotEqual(t, updating, true, "Item Should not be in updating state")
 }
 
 func TestConcurrentRevokeAndGetValueBug(t *testing.T) {
+	t.Parallel()
 	// Run refresh & revoke loops quicker than usual
-	defaultLoopInterval := loopInterval
-	defer func() {
-		stop()
-		loopInterval = defaultLoopInterval
-	}()
-	loopInterval = 10 * time.Millisecond
-	StartWith(1, 11, 1, 20*time.Millisecond)
+	c := mustNew(t, WithWorkers(1), WithQueueSize(11), WithMaxEntries(1), WithDefaultTTL(20*time.Millisecond), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
 	// Continuously spamming GetValue should force ConcurrentRefreshAndGetBug to manifest if it is present
-	c := make(chan []byte, 1)
+	done := make(chan []byte, 1)
 	key := "TestConcurrentRevokeAndGetValueBug"
-	go busyGet(t, c, key)
-	value := randomGetFunc("")
+	go busyGet(c, done, key)
+	value, _ := randomGetFunc(context.Background(), "")
 	i := CacheItem{
 		Key:        key,
 		Value:      value,
 		Expiration: 15 * time.Millisecond,
 		GetFunc:    randomGetFunc,
 	}
-	AddItem(i)
+	c.AddItem(i)
 	// sleep long enough for the bug to kick in repeatably
 	time.Sleep(1 * time.Second)
-	c <- []byte("stop")
+	done <- []byte("stop")
 	time.Sleep(53 * time.Millisecond)
-	assert.True(t, nil == GetValue(key), "Item should have been revoked by now")
+	assert.True(t, nil == c.GetValue(key), "Item should have been revoked by now")
+}
+
+func TestIndependentCaches(t *testing.T) {
+	t.Parallel()
+	c1 := mustNew(t, WithWorkers(1), WithQueueSize(1), WithMaxEntries(1), WithDefaultTTL(1*time.Second))
+	c1.Start()
+	defer c1.Close(context.Background())
+	c2 := mustNew(t, WithWorkers(1), WithQueueSize(1), WithMaxEntries(2), WithDefaultTTL(1*time.Second))
+	c2.Start()
+	defer c2.Close(context.Background())
+	for _, key := range []string{"TestIndependentCaches1", "TestIndependentCaches2"} {
+		i := CacheItem{
+			Key:        key,
+			Value:      []byte(key),
+			Expiration: 1 * time.Second,
+			GetFunc:    noopGetFunc,
+		}
+		c1.AddItem(i)
+		c2.AddItem(i)
+	}
+	assert.Equal(t, 1, count(c1.Cache), "First cache should hold only one item")
+	assert.Equal(t, 2, count(c2.Cache), "Second cache should hold both items")
+	assert.Nil(t, c1.GetValue("TestIndependentCaches1"), "Item #1 should have been revoked from first cache when it was full")
+	assert.Equal(t, "TestIndependentCaches1", string(c2.GetValue("TestIndependentCaches1")))
+}
+
+func TestTypedCache(t *testing.T) {
+	t.Parallel()
+	type article struct {
+		ID    int
+		Title string
+	}
+	c, err := New[int, article](WithWorkers(1), WithLoopInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Should have created cache: %v", err)
+	}
+	c.Start()
+	defer c.Close(context.Background())
+	c.Set(Item[int, article]{
+		Key:        1,
+		Value:      article{ID: 1, Title: "first"},
+		Expiration: 10 * time.Millisecond,
+		Loader: func(ctx context.Context, id int) (article, error) {
+			return article{ID: id, Title: "refreshed"}, nil
+		},
+	})
+	value, ok := c.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, article{ID: 1, Title: "first"}, value)
+	time.Sleep(30 * time.Millisecond)
+	value, ok = c.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, article{ID: 1, Title: "refreshed"}, value, "Item should have been refreshed by its loader")
+	_, ok = c.Get(2)
+	assert.False(t, ok)
+}
+
+func TestFailingLoaderKeepsOldValue(t *testing.T) {
+	t.Parallel()
+	c, err := New[string, int](WithWorkers(1), WithLoopInterval(10*time.Millisecond), WithRetryPolicy(fastRetry))
+	if err != nil {
+		t.Fatalf("Should have created cache: %v", err)
+	}
+	c.Start()
+	defer c.Close(context.Background())
+	c.Set(Item[string, int]{
+		Key:        "TestFailingLoaderKeepsOldValue",
+		Value:      1,
+		Expiration: 10 * time.Millisecond,
+		Loader: func(ctx context.Context, key string) (int, error) {
+			return 0, errors.New("origin down")
+		},
+	})
+	time.Sleep(30 * time.Millisecond)
+	value, ok := c.Get("TestFailingLoaderKeepsOldValue")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value, "Old value should be kept when loader fails")
+	info, ok := c.Inspect("TestFailingLoaderKeepsOldValue")
+	assert.True(t, ok)
+	assert.EqualError(t, info.LastError, "origin down")
+	assert.False(t, info.LastErrorTime.IsZero())
+	assert.True(t, info.Failures >= 2, "Failures in a row should be counted, got %d", info.Failures)
+}
+
+func TestGetFuncErrorIsRecordedAndLogged(t *testing.T) {
+	t.Parallel()
+	var logs bytes.Buffer
+	var mu sync.Mutex
+	fail := true
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(10*time.Millisecond), WithRetryPolicy(fastRetry), WithLogger(slog.New(slog.NewTextHandler(&syncWriter{w: &logs, mu: &mu}, nil))))
+	c.Start()
+	defer c.Close(context.Background())
+	key := "TestGetFuncErrorIsRecordedAndLogged"
+	c.AddItem(CacheItem{
+		Key:        key,
+		Value:      []byte("old"),
+		Expiration: 10 * time.Millisecond,
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if fail {
+				return nil, errors.New("origin down")
+			}
+			return []byte("new"), nil
+		},
+	})
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, "old", string(c.GetValue(key)), "Old value should be kept when GetFunc fails")
+	info, _ := c.Inspect(key)
+	assert.True(t, info.Failures > 0)
+	mu.Lock()
+	assert.Contains(t, logs.String(), "level=WARN msg=\"Refreshing cache item failed, keeping old value\" key=TestGetFuncErrorIsRecordedAndLogged")
+	assert.Contains(t, logs.String(), "error=\"origin down\"")
+	fail = false
+	mu.Unlock()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, "new", string(c.GetValue(key)))
+	info, _ = c.Inspect(key)
+	assert.Equal(t, 0, info.Failures, "Failure count should be reset by a successful refresh")
+	assert.EqualError(t, info.LastError, "origin down", "Last error should be kept for inspection")
 }
 
-func noopGetFunc(s string) []byte {
-	return nil
+func TestRefreshTimeout(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
+	key := "TestRefreshTimeout"
+	c.AddItem(CacheItem{
+		Key:            key,
+		Value:          []byte("old"),
+		Expiration:     10 * time.Millisecond,
+		RefreshTimeout: 10 * time.Millisecond,
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "old", string(c.GetValue(key)), "Old value should be kept when refresh times out")
+	info, _ := c.Inspect(key)
+	assert.True(t, errors.Is(info.LastError, context.DeadlineExceeded), "Timed out refresh should fail, got %v", info.LastError)
+	assert.True(t, info.Failures > 0)
 }
 
-func randomGetFunc(s string) []byte {
-	return []byte(uuid.New().String())
+func TestHungGetFuncDoesNotHoldWorker(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(10*time.Millisecond), WithRefreshTimeout(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	c.AddItem(CacheItem{
+		Key:        "TestHungGetFuncDoesNotHoldWorker1",
+		Value:      []byte("hung"),
+		Expiration: 10 * time.Millisecond,
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			// ignores its context
+			<-release
+			return nil, nil
+		},
+	})
+	key := "TestHungGetFuncDoesNotHoldWorker2"
+	value := []byte("old")
+	c.AddItem(CacheItem{
+		Key:        key,
+		Value:      value,
+		Expiration: 10 * time.Millisecond,
+		GetFunc:    randomGetFunc,
+	})
+	time.Sleep(80 * time.Millisecond)
+	assert.NotEqual(t, string(value), string(c.GetValue(key)), "Only worker should have moved on from hung GetFunc and refreshed the other item")
+}
+
+// retry failed refreshes on next loop
+var fastRetry = RetryPolicy{InitialInterval: 1 * time.Millisecond, MaxInterval: 1 * time.Millisecond, Multiplier: 1}
+
+func mustNew(t *testing.T, opts ...Option) *BytesCache {
+	c, err := NewBytes(opts...)
+	if err != nil {
+		t.Fatalf("Should have created cache: %v", err)
+	}
+	return c
 }
 
-func busyGet(t *testing.T, c chan []byte, k string) {
+func noopGetFunc(ctx context.Context, s string) ([]byte, error) {
+	return nil, nil
+}
+
+func randomGetFunc(ctx context.Context, s string) ([]byte, error) {
+	return []byte(uuid.New().String()), nil
+}
+
+// serializes writes of background logging with reads of the test
+type syncWriter struct {
+	w  io.Writer
+	mu *sync.Mutex
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+func busyGet(c *BytesCache, done chan []byte, k string) {
 	defer func() {
-		_ = <-c
+		_ = <-done
 	}()
-	for len(c) == 0 {
-		_ = GetValue(k)
+	for len(done) == 0 {
+		_ = c.GetValue(k)
 	}
 }
+
+// copy of the entry for key, taken under lock
+func peek[K comparable, V any](c *Cache[K, V], key K) (entry[K, V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return entry[K, V]{}, false
+	}
+	return *e, true
+}
+
+func count[K comparable, V any](c *Cache[K, V]) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
@@ -0,0 +1,301 @@
+package gocachelib
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrInvalidConfig is returned by New when the given options do not make a working cache
+var ErrInvalidConfig = errors.New("invalid cache configuration")
+
+type config struct {
+	// name telling caches apart in metrics, default "default"
+	name string
+
+	// how many simultaneus workers should we have, default 20
+	workers int
+
+	// autoscaling of workers starting from workers, nil for fixed amount
+	scaling *ScalingPolicy
+
+	// maximum amount of jobs buffered, default 200
+	queueSize int
+
+	// what is done with refreshes when queue is full, default DropNewest
+	queueOverflow OverflowPolicy
+
+	// maximum amount of events waiting for hooks, default 1000
+	hookBuffer int
+
+	// maximum amount of items in cache, default 20, or no limit if only maxBytes is set
+	maxEntries    int
+	maxEntriesSet bool
+
+	// budget in bytes for cached items, default 0 for no limit
+	maxBytes int64
+
+	// Sizer[K, V] counting sizes of items against maxBytes, nil for default
+	sizer any
+
+	// EvictionPolicy[K] choosing items to remove when cache is full, nil for earliest revoke time
+	eviction any
+
+	// ttl of items not defining their own, default 1 hour
+	defaultTTL time.Duration
+
+	// expiration of items not defining their own, default 1 minute
+	defaultExpiration time.Duration
+
+	// how long keys are remembered missing at the origin, default 30 seconds
+	negativeTTL time.Duration
+
+	// revoke & refresh loop interval, default 1 second
+	loopInterval time.Duration
+
+	// how long before expiration items are queued for refresh, default 300ms
+	refreshLeadTime time.Duration
+
+	// fraction of expiration by which expire times are randomly spread, default 0
+	expirationJitter float64
+
+	// how eagerly items are refreshed before expiring in proportion to their load time, default 0 for never
+	earlyRefreshBeta float64
+
+	// how long loaders may take refreshing items not defining their own timeout, default 30 seconds
+	refreshTimeout time.Duration
+
+	// how long after expiring values of items not defining their own bound are served while refreshing
+	// and while refreshes fail, default 0 for no limit
+	maxStale     time.Duration
+	staleIfError time.Duration
+
+	// what is done with values staler than their bound, default ServeStale
+	stalePolicy StalePolicy
+
+	// how failed refreshes of items not defining their own policy are retried
+	retryPolicy RetryPolicy
+
+	// circuit breakers of loader groups
+	breakerPolicy   BreakerPolicy
+	onBreakerChange func(group string, from, to BreakerState)
+
+	// where background events and refresh errors are logged, default slog.Default()
+	logger Logger
+}
+
+func defaultConfig() config {
+	return config{
+		name:              "default",
+		workers:           20,
+		queueSize:         200,
+		hookBuffer:        1000,
+		maxEntries:        20,
+		defaultTTL:        1 * time.Hour,
+		defaultExpiration: 1 * time.Minute,
+		negativeTTL:       30 * time.Second,
+		loopInterval:      1 * time.Second,
+		refreshLeadTime:   300 * time.Millisecond,
+		refreshTimeout:    30 * time.Second,
+		retryPolicy:       defaultRetryPolicy(),
+		breakerPolicy:     defaultBreakerPolicy(),
+		logger:            slog.Default(),
+	}
+}
+
+// Option configures a cache created with New
+type Option func(*config)
+
+// WithName sets the name telling cache apart from others in metrics
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithWorkers sets the amount of workers refreshing expired items, replacing WithAutoscaling
+func WithWorkers(n int) Option {
+	return func(c *config) {
+		c.workers = n
+		c.scaling = nil
+	}
+}
+
+// WithQueueSize sets the maximum amount of refresh jobs buffered for workers
+func WithQueueSize(n int) Option {
+	return func(c *config) {
+		c.queueSize = n
+	}
+}
+
+// WithQueueOverflow sets what is done with refreshes due when refresh queue is full.
+// Dropped refreshes are tried again on a later loop.
+func WithQueueOverflow(p OverflowPolicy) Option {
+	return func(c *config) {
+		c.queueOverflow = p
+	}
+}
+
+// WithHookBuffer sets the maximum amount of events waiting for hooks. Events are dropped while
+// hooks are this far behind.
+func WithHookBuffer(n int) Option {
+	return func(c *config) {
+		c.hookBuffer = n
+	}
+}
+
+// WithMaxEntries sets the maximum amount of items in cache. Caches limited by WithMaxBytes have no
+// entry limit unless this is given too.
+func WithMaxEntries(n int) Option {
+	return func(c *config) {
+		c.maxEntries = n
+		c.maxEntriesSet = true
+	}
+}
+
+// WithDefaultTTL sets the TTL of items not defining their own
+func WithDefaultTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.defaultTTL = d
+	}
+}
+
+// WithDefaultExpiration sets the expiration of items not defining their own, like those loaded by GetOrLoad
+func WithDefaultExpiration(d time.Duration) Option {
+	return func(c *config) {
+		c.defaultExpiration = d
+	}
+}
+
+// WithNegativeTTL sets how long keys are remembered missing after loader returned ErrNotFound
+func WithNegativeTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.negativeTTL = d
+	}
+}
+
+// WithLoopInterval sets how often expiring items are refreshed and unused items revoked
+func WithLoopInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.loopInterval = d
+	}
+}
+
+// WithRefreshLeadTime sets how long before their expiration items are queued for refresh
+func WithRefreshLeadTime(d time.Duration) Option {
+	return func(c *config) {
+		c.refreshLeadTime = d
+	}
+}
+
+// WithExpirationJitter sets the fraction, between 0 and 1, of expiration by which expire times
+// are randomly shortened or lengthened, so that items added together do not all expire together
+func WithExpirationJitter(fraction float64) Option {
+	return func(c *config) {
+		c.expirationJitter = fraction
+	}
+}
+
+// WithEarlyRefresh enables XFetch style probabilistic early refresh. Each time an item expires
+// it is refreshed earlier by a random amount, on average beta times how long its last refresh took.
+// Beta 1 is a good start, larger values refresh earlier.
+func WithEarlyRefresh(beta float64) Option {
+	return func(c *config) {
+		c.earlyRefreshBeta = beta
+	}
+}
+
+// WithRefreshTimeout sets how long loaders may take refreshing items not defining their own timeout.
+// Timed out refreshes fail and the old value is kept.
+func WithRefreshTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.refreshTimeout = d
+	}
+}
+
+// WithRetryPolicy sets how failed refreshes of items not defining their own policy are retried.
+// By default retries begin after 1 second and double up to 5 minutes, with 20% jitter and no attempt limit.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *config) {
+		c.retryPolicy = p
+	}
+}
+
+// WithCircuitBreaker sets the policy of circuit breakers of loader groups.
+// By default 5 failed refreshes in a row open the breaker for 30 seconds, after which 1 trial refresh is let through.
+func WithCircuitBreaker(p BreakerPolicy) Option {
+	return func(c *config) {
+		c.breakerPolicy = p
+	}
+}
+
+// WithBreakerStateChange sets a callback called whenever circuit breaker of a loader group changes state.
+// It is called in order with hooks on their goroutine, and not after Close.
+func WithBreakerStateChange(f func(group string, from, to BreakerState)) Option {
+	return func(c *config) {
+		c.onBreakerChange = f
+	}
+}
+
+// WithLogger sets where background events and refresh errors are logged, for example a *slog.Logger.
+// NopLogger silences logging.
+func WithLogger(l Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+func (c config) validate() error {
+	switch {
+	case c.name == "":
+		return fmt.Errorf("%w: name must not be empty", ErrInvalidConfig)
+	case c.workers <= 0 && c.scaling == nil:
+		return fmt.Errorf("%w: workers must be positive, got %d", ErrInvalidConfig, c.workers)
+	case c.queueSize <= 0:
+		return fmt.Errorf("%w: queue size must be positive, got %d", ErrInvalidConfig, c.queueSize)
+	case c.queueOverflow < DropNewest || c.queueOverflow > Grow:
+		return fmt.Errorf("%w: unknown queue overflow policy %d", ErrInvalidConfig, c.queueOverflow)
+	case c.hookBuffer <= 0:
+		return fmt.Errorf("%w: hook buffer must be positive, got %d", ErrInvalidConfig, c.hookBuffer)
+	case c.maxEntries <= 0:
+		return fmt.Errorf("%w: max entries must be positive, got %d", ErrInvalidConfig, c.maxEntries)
+	case c.maxBytes < 0:
+		return fmt.Errorf("%w: max bytes must not be negative, got %d", ErrInvalidConfig, c.maxBytes)
+	case c.defaultTTL <= 0:
+		return fmt.Errorf("%w: default TTL must be positive, got %v", ErrInvalidConfig, c.defaultTTL)
+	case c.defaultExpiration <= 0:
+		return fmt.Errorf("%w: default expiration must be positive, got %v", ErrInvalidConfig, c.defaultExpiration)
+	case c.negativeTTL <= 0:
+		return fmt.Errorf("%w: negative TTL must be positive, got %v", ErrInvalidConfig, c.negativeTTL)
+	case c.loopInterval <= 0:
+		return fmt.Errorf("%w: loop interval must be positive, got %v", ErrInvalidConfig, c.loopInterval)
+	case c.refreshLeadTime < 0:
+		return fmt.Errorf("%w: refresh lead time must not be negative, got %v", ErrInvalidConfig, c.refreshLeadTime)
+	case c.expirationJitter < 0 || c.expirationJitter > 1:
+		return fmt.Errorf("%w: expiration jitter must be between 0 and 1, got %v", ErrInvalidConfig, c.expirationJitter)
+	case c.earlyRefreshBeta < 0:
+		return fmt.Errorf("%w: early refresh beta must not be negative, got %v", ErrInvalidConfig, c.earlyRefreshBeta)
+	case c.maxStale < 0:
+		return fmt.Errorf("%w: max stale must not be negative, got %v", ErrInvalidConfig, c.maxStale)
+	case c.staleIfError < 0:
+		return fmt.Errorf("%w: stale if error must not be negative, got %v", ErrInvalidConfig, c.staleIfError)
+	case c.stalePolicy < ServeStale || c.stalePolicy > DropStale:
+		return fmt.Errorf("%w: unknown stale policy %d", ErrInvalidConfig, c.stalePolicy)
+	case c.refreshTimeout <= 0:
+		return fmt.Errorf("%w: refresh timeout must be positive, got %v", ErrInvalidConfig, c.refreshTimeout)
+	case c.logger == nil:
+		return fmt.Errorf("%w: logger must not be nil", ErrInvalidConfig)
+	case c.loopInterval > c.defaultTTL:
+		return fmt.Errorf("%w: loop interval %v exceeds default TTL %v, items would outlive their TTL", ErrInvalidConfig, c.loopInterval, c.defaultTTL)
+	}
+	if c.scaling != nil {
+		if err := c.scaling.validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.retryPolicy.validate(); err != nil {
+		return err
+	}
+	return c.breakerPolicy.validate()
+}
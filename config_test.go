@@ -0,0 +1,114 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithDefaults(t *testing.T) {
+	c, err := New[string, []byte]()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultConfig(), c.cfg)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	breakerPolicy := BreakerPolicy{FailureThreshold: 3, OpenTimeout: 1 * time.Minute, HalfOpenMaxCalls: 2}
+	retryPolicy := RetryPolicy{InitialInterval: 1 * time.Second, MaxInterval: 1 * time.Minute, Multiplier: 3, Jitter: 0.1, MaxAttempts: 5}
+	c, err := New[string, []byte](
+		WithName("articles"),
+		WithWorkers(2),
+		WithQueueSize(3),
+		WithQueueOverflow(DropOldest),
+		WithHookBuffer(9),
+		WithMaxEntries(4),
+		WithMaxBytes(1024),
+		WithDefaultTTL(5*time.Minute),
+		WithDefaultExpiration(30*time.Second),
+		WithNegativeTTL(10*time.Second),
+		WithLoopInterval(6*time.Second),
+		WithRefreshLeadTime(7*time.Millisecond),
+		WithExpirationJitter(0.1),
+		WithEarlyRefresh(1.5),
+		WithRefreshTimeout(8*time.Second),
+		WithMaxStale(1*time.Minute),
+		WithStaleIfError(1*time.Hour),
+		WithStalePolicy(DropStale),
+		WithRetryPolicy(retryPolicy),
+		WithCircuitBreaker(breakerPolicy),
+		WithLogger(logger),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, config{
+		name:              "articles",
+		workers:           2,
+		queueSize:         3,
+		queueOverflow:     DropOldest,
+		hookBuffer:        9,
+		maxEntries:        4,
+		maxEntriesSet:     true,
+		maxBytes:          1024,
+		defaultTTL:        5 * time.Minute,
+		defaultExpiration: 30 * time.Second,
+		negativeTTL:       10 * time.Second,
+		loopInterval:      6 * time.Second,
+		refreshLeadTime:   7 * time.Millisecond,
+		expirationJitter:  0.1,
+		earlyRefreshBeta:  1.5,
+		refreshTimeout:    8 * time.Second,
+		maxStale:          1 * time.Minute,
+		staleIfError:      1 * time.Hour,
+		stalePolicy:       DropStale,
+		retryPolicy:       retryPolicy,
+		breakerPolicy:     breakerPolicy,
+		logger:            logger,
+	}, c.cfg)
+}
+
+func TestNewWithInvalidOptions(t *testing.T) {
+	tests := map[string][]Option{
+		"zero workers":                 {WithWorkers(0)},
+		"negative queue size":          {WithQueueSize(-1)},
+		"zero max entries":             {WithMaxEntries(0)},
+		"zero hook buffer":             {WithHookBuffer(0)},
+		"zero default TTL":             {WithDefaultTTL(0)},
+		"zero default expiration":      {WithDefaultExpiration(0)},
+		"expiration jitter over 1":     {WithExpirationJitter(1.5)},
+		"negative early refresh beta":  {WithEarlyRefresh(-1)},
+		"unknown queue overflow":       {WithQueueOverflow(OverflowPolicy(7))},
+		"autoscaling max below min":    {WithAutoscaling(ScalingPolicy{MinWorkers: 2, MaxWorkers: 1, QueueDepth: 1, QueueWait: 1 * time.Second, IdleTimeout: 1 * time.Minute})},
+		"autoscaling without idle":     {WithAutoscaling(ScalingPolicy{MinWorkers: 1, MaxWorkers: 2, QueueDepth: 1, QueueWait: 1 * time.Second})},
+		"empty name":                   {WithName("")},
+		"negative max bytes":           {WithMaxBytes(-1)},
+		"zero negative TTL":            {WithNegativeTTL(0)},
+		"zero loop interval":           {WithLoopInterval(0)},
+		"negative refresh lead time":   {WithRefreshLeadTime(-1 * time.Millisecond)},
+		"zero refresh timeout":         {WithRefreshTimeout(0)},
+		"negative max stale":           {WithMaxStale(-1 * time.Second)},
+		"negative stale if error":      {WithStaleIfError(-1 * time.Second)},
+		"unknown stale policy":         {WithStalePolicy(StalePolicy(7))},
+		"nil logger":                   {WithLogger(nil)},
+		"loop interval exceeds TTL":    {WithDefaultTTL(1 * time.Second), WithLoopInterval(2 * time.Second)},
+		"default loop interval vs TTL": {WithDefaultTTL(10 * time.Millisecond)},
+	}
+	for name, opts := range tests {
+		c, err := New[string, []byte](opts...)
+		assert.Nil(t, c, name)
+		assert.True(t, errors.Is(err, ErrInvalidConfig), "%s: should have got ErrInvalidConfig, got %v", name, err)
+	}
+}
+
+func TestNopLogger(t *testing.T) {
+	t.Parallel()
+	c, err := New[string, []byte](WithLogger(NopLogger{}))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Start())
+	_, err = c.Close(context.Background())
+	assert.NoError(t, err)
+}
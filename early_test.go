@@ -0,0 +1,66 @@
+package gocachelib
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpirationJitterSpreadsExpireTimes(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithMaxEntries(100), WithExpirationJitter(0.5))
+	c.Start()
+	defer c.Close(context.Background())
+	start := time.Now()
+	expireTimes := map[time.Time]bool{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint(i)
+		c.AddItem(CacheItem{Key: key, Value: []byte(key), Expiration: 1 * time.Minute})
+		info, _ := c.Inspect(key)
+		assert.False(t, info.ExpireTime.Before(start.Add(30*time.Second)), "Expire time should be at most half earlier")
+		assert.False(t, info.ExpireTime.After(time.Now().Add(90*time.Second)), "Expire time should be at most half later")
+		expireTimes[info.ExpireTime.Truncate(time.Second)] = true
+	}
+	assert.True(t, len(expireTimes) > 10, "Expire times should be spread, got %d distinct seconds", len(expireTimes))
+}
+
+func TestEarlyRefreshWeightedByLoadDuration(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithMaxEntries(100), WithEarlyRefresh(1))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var total time.Duration
+	for i := 0; i < 100; i++ {
+		e := &entry[string, []byte]{Item: Item[string, []byte]{Expiration: 1 * time.Hour}}
+		c.updateExpireTime(e)
+		assert.Equal(t, time.Duration(0), e.earlyBy, "Items never loaded should not be refreshed early")
+		e.LoadDuration = 1 * time.Second
+		c.updateExpireTime(e)
+		assert.True(t, e.earlyBy >= 0)
+		assert.Equal(t, e.ExpireTime.Add(-c.cfg.refreshLeadTime-e.earlyBy), e.refreshAt(c.cfg.refreshLeadTime))
+		total += e.earlyBy
+	}
+	mean := total / 100
+	assert.True(t, mean > 500*time.Millisecond && mean < 2*time.Second, "Items should be refreshed on average load duration early, got %v", mean)
+	e := &entry[string, []byte]{Item: Item[string, []byte]{Expiration: 10 * time.Millisecond}, LoadDuration: 1 * time.Hour}
+	c.updateExpireTime(e)
+	assert.True(t, e.earlyBy <= e.Expiration, "Item should not be refreshed earlier than its expiration")
+}
+
+func TestRefreshRecordsLoadDuration(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithLoopInterval(10*time.Millisecond), WithRefreshLeadTime(0), WithEarlyRefresh(1))
+	c.Start()
+	defer c.Close(context.Background())
+	key := "TestRefreshRecordsLoadDuration"
+	c.AddItem(CacheItem{Key: key, Value: []byte("old"), Expiration: 20 * time.Millisecond, GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+		time.Sleep(5 * time.Millisecond)
+		return []byte("new"), nil
+	}})
+	time.Sleep(100 * time.Millisecond)
+	info, _ := c.Inspect(key)
+	assert.True(t, info.LoadDuration >= 5*time.Millisecond, "Load duration should be recorded, got %v", info.LoadDuration)
+}
@@ -0,0 +1,212 @@
+package gocachelib
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// EvictionPolicy chooses which item to remove when cache is full. Cache notifies it of keys
+// inserted, accessed by Get and Lookup, updated and removed, and asks it for a victim to make room.
+// Cache calls it with its lock held, so it need not be safe for concurrent use, but one policy
+// must not be shared between caches.
+type EvictionPolicy[K comparable] interface {
+	OnInsert(key K)
+	OnAccess(key K)
+	OnUpdate(key K)
+	OnRemove(key K)
+	// Victim key to remove next, false if policy has no keys
+	Victim() (K, bool)
+}
+
+// WithEvictionPolicy sets the policy choosing items to remove when cache is full, for example
+// NewLRU, NewLFU, NewTinyLFU or NewARC. By default the item with earliest revoke time is removed.
+// Key type must match that of the cache.
+func WithEvictionPolicy[K comparable](p EvictionPolicy[K]) Option {
+	return func(c *config) {
+		c.eviction = p
+	}
+}
+
+func policyOf[K comparable](cfg config) (EvictionPolicy[K], error) {
+	if cfg.eviction == nil {
+		return nil, nil
+	}
+	policy, ok := cfg.eviction.(EvictionPolicy[K])
+	if !ok {
+		return nil, fmt.Errorf("%w: eviction policy %T does not match cache of %T keys", ErrInvalidConfig, cfg.eviction, *new(K))
+	}
+	return policy, nil
+}
+
+// keys in recency order, most recent first
+type keyList[K comparable] struct {
+	order *list.List
+	elems map[K]*list.Element
+}
+
+func newKeyList[K comparable]() *keyList[K] {
+	return &keyList[K]{order: list.New(), elems: map[K]*list.Element{}}
+}
+
+func (l *keyList[K]) len() int {
+	return l.order.Len()
+}
+
+func (l *keyList[K]) contains(key K) bool {
+	_, ok := l.elems[key]
+	return ok
+}
+
+func (l *keyList[K]) pushFront(key K) {
+	if elem, ok := l.elems[key]; ok {
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.elems[key] = l.order.PushFront(key)
+}
+
+// move key to front, false if it is not in list
+func (l *keyList[K]) touch(key K) bool {
+	elem, ok := l.elems[key]
+	if ok {
+		l.order.MoveToFront(elem)
+	}
+	return ok
+}
+
+// remove key, false if it was not in list
+func (l *keyList[K]) remove(key K) bool {
+	elem, ok := l.elems[key]
+	if ok {
+		l.order.Remove(elem)
+		delete(l.elems, key)
+	}
+	return ok
+}
+
+// least recent key
+func (l *keyList[K]) back() (K, bool) {
+	elem := l.order.Back()
+	if elem == nil {
+		var zero K
+		return zero, false
+	}
+	return elem.Value.(K), true
+}
+
+// keys from least to most recent
+func (l *keyList[K]) oldestFirst() []K {
+	keys := make([]K, 0, l.order.Len())
+	for elem := l.order.Back(); elem != nil; elem = elem.Prev() {
+		keys = append(keys, elem.Value.(K))
+	}
+	return keys
+}
+
+// remove and return least recent key
+func (l *keyList[K]) popBack() (K, bool) {
+	key, ok := l.back()
+	if ok {
+		l.remove(key)
+	}
+	return key, ok
+}
+
+// LRU evicts the least recently used item
+type LRU[K comparable] struct {
+	keys *keyList[K]
+}
+
+// NewLRU creates a least recently used eviction policy
+func NewLRU[K comparable]() *LRU[K] {
+	return &LRU[K]{keys: newKeyList[K]()}
+}
+
+func (p *LRU[K]) OnInsert(key K) { p.keys.pushFront(key) }
+func (p *LRU[K]) OnAccess(key K) { p.keys.touch(key) }
+func (p *LRU[K]) OnUpdate(key K) { p.keys.touch(key) }
+func (p *LRU[K]) OnRemove(key K) { p.keys.remove(key) }
+
+func (p *LRU[K]) Victim() (K, bool) {
+	return p.keys.back()
+}
+
+// LFU evicts the least frequently used item, the least recently used one of those equally frequent
+type LFU[K comparable] struct {
+	freqs   map[K]int
+	buckets map[int]*keyList[K]
+	minFreq int
+}
+
+// NewLFU creates a least frequently used eviction policy
+func NewLFU[K comparable]() *LFU[K] {
+	return &LFU[K]{freqs: map[K]int{}, buckets: map[int]*keyList[K]{}}
+}
+
+func (p *LFU[K]) OnInsert(key K) {
+	if _, ok := p.freqs[key]; ok {
+		p.OnUpdate(key)
+		return
+	}
+	p.freqs[key] = 1
+	p.bucket(1).pushFront(key)
+	p.minFreq = 1
+}
+
+func (p *LFU[K]) OnAccess(key K) {
+	freq, ok := p.freqs[key]
+	if !ok {
+		return
+	}
+	p.unlink(key, freq)
+	p.freqs[key] = freq + 1
+	p.bucket(freq + 1).pushFront(key)
+	if p.minFreq == freq && p.buckets[freq] == nil {
+		p.minFreq = freq + 1
+	}
+}
+
+func (p *LFU[K]) OnUpdate(key K) { p.OnAccess(key) }
+
+func (p *LFU[K]) OnRemove(key K) {
+	freq, ok := p.freqs[key]
+	if !ok {
+		return
+	}
+	delete(p.freqs, key)
+	p.unlink(key, freq)
+	if p.minFreq == freq && p.buckets[freq] == nil {
+		p.minFreq = 0
+		for f := range p.buckets {
+			if p.minFreq == 0 || f < p.minFreq {
+				p.minFreq = f
+			}
+		}
+	}
+}
+
+func (p *LFU[K]) Victim() (K, bool) {
+	if b, ok := p.buckets[p.minFreq]; ok {
+		return b.back()
+	}
+	var zero K
+	return zero, false
+}
+
+func (p *LFU[K]) bucket(freq int) *keyList[K] {
+	b, ok := p.buckets[freq]
+	if !ok {
+		b = newKeyList[K]()
+		p.buckets[freq] = b
+	}
+	return b
+}
+
+// remove key from its frequency bucket, dropping the bucket when it empties
+func (p *LFU[K]) unlink(key K, freq int) {
+	b := p.buckets[freq]
+	b.remove(key)
+	if b.len() == 0 {
+		delete(p.buckets, freq)
+	}
+}
@@ -0,0 +1,156 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func victim[K comparable](t *testing.T, p EvictionPolicy[K]) K {
+	key, ok := p.Victim()
+	assert.True(t, ok, "Policy should have a victim")
+	return key
+}
+
+func TestLRU(t *testing.T) {
+	t.Parallel()
+	p := NewLRU[string]()
+	_, ok := p.Victim()
+	assert.False(t, ok)
+	p.OnInsert("a")
+	p.OnInsert("b")
+	p.OnInsert("c")
+	assert.Equal(t, "a", victim[string](t, p))
+	p.OnAccess("a")
+	assert.Equal(t, "b", victim[string](t, p))
+	p.OnUpdate("b")
+	assert.Equal(t, "c", victim[string](t, p))
+	p.OnRemove("c")
+	assert.Equal(t, "a", victim[string](t, p))
+}
+
+func TestLFU(t *testing.T) {
+	t.Parallel()
+	p := NewLFU[string]()
+	_, ok := p.Victim()
+	assert.False(t, ok)
+	p.OnInsert("a")
+	p.OnInsert("b")
+	p.OnInsert("c")
+	p.OnAccess("a")
+	p.OnAccess("a")
+	p.OnAccess("b")
+	assert.Equal(t, "c", victim[string](t, p))
+	p.OnRemove("c")
+	assert.Equal(t, "b", victim[string](t, p), "Least frequent should be evicted")
+	p.OnAccess("b")
+	p.OnAccess("b")
+	assert.Equal(t, "a", victim[string](t, p), "Least recent of equally frequent should be evicted")
+	p.OnRemove("a")
+	p.OnRemove("b")
+	_, ok = p.Victim()
+	assert.False(t, ok)
+}
+
+func TestTinyLFUKeepsFrequentItems(t *testing.T) {
+	t.Parallel()
+	p := NewTinyLFU[string](100)
+	for i := 0; i < 99; i++ {
+		key := fmt.Sprint("hot", i)
+		p.OnInsert(key)
+		p.OnAccess(key)
+		p.OnAccess(key)
+	}
+	// a one-off item in the window loses to the frequently used main area
+	p.OnInsert("cold")
+	assert.Equal(t, "cold", victim[string](t, p))
+	p.OnRemove("cold")
+	// a frequently used one wins
+	for i := 0; i < 10; i++ {
+		p.OnAccess("hot98")
+	}
+	p.OnInsert("popular")
+	for i := 0; i < 10; i++ {
+		p.OnAccess("popular")
+	}
+	assert.NotEqual(t, "popular", victim[string](t, p))
+}
+
+func TestSketchRowsAreIndependent(t *testing.T) {
+	t.Parallel()
+	s := newSketch(2)
+	hot := uint64(0x9e3779b97f4a7c15)
+	for i := 0; i < 10; i++ {
+		s.increment(hot)
+	}
+	assert.Equal(t, uint8(10), s.estimate(hot))
+	// keys sharing the counter of the first row should not share those of every row
+	colliding := 0
+	for h := uint64(1); colliding < 20; h++ {
+		cold := h * 0xbf58476d1ce4e5b9
+		if cold == hot || s.index(cold, 0) != s.index(hot, 0) {
+			continue
+		}
+		colliding++
+		assert.True(t, s.estimate(cold) < s.estimate(hot), "Colliding key %x should be told apart by other rows", cold)
+	}
+}
+
+func TestARCAdapts(t *testing.T) {
+	t.Parallel()
+	p := NewARC[string](2)
+	p.OnInsert("a")
+	p.OnInsert("b")
+	p.OnAccess("a")
+	assert.Equal(t, "b", victim[string](t, p), "Items used once should be evicted first")
+	p.OnRemove("b")
+	p.OnInsert("c")
+	assert.Equal(t, "c", victim[string](t, p))
+	// inserting a ghost of an item used once favours recency
+	p.OnInsert("b")
+	assert.Equal(t, 1, p.p)
+	assert.True(t, p.t2.contains("b"), "Returning ghost should count as used more than once")
+}
+
+func TestCacheWithEvictionPolicy(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithMaxEntries(3), WithEvictionPolicy[string](NewLRU[string]()))
+	c.Start()
+	defer c.Close(context.Background())
+	for _, key := range []string{"a", "b", "c"} {
+		c.AddItem(CacheItem{Key: key, Value: []byte(key)})
+	}
+	c.GetValue("a")
+	c.AddItem(CacheItem{Key: "b", Value: []byte("updated")})
+	c.AddItem(CacheItem{Key: "d", Value: []byte("d")})
+	assert.Nil(t, c.GetValue("c"), "Least recently used item should have been evicted")
+	assert.Equal(t, 3, count(c.Cache))
+
+	_, err := New[int, []byte](WithEvictionPolicy[string](NewLRU[string]()))
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Policy of other key type should be rejected")
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	t.Parallel()
+	policies := map[string]EvictionPolicy[string]{
+		"LRU":     NewLRU[string](),
+		"LFU":     NewLFU[string](),
+		"TinyLFU": NewTinyLFU[string](10),
+		"ARC":     NewARC[string](10),
+	}
+	for name, policy := range policies {
+		c := mustNew(t, WithMaxEntries(10), WithEvictionPolicy(policy))
+		c.Start()
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprint(i % 30)
+			if c.GetValue(key) == nil {
+				assert.NoError(t, c.AddItem(CacheItem{Key: key, Value: []byte(key)}), name)
+			}
+		}
+		assert.Equal(t, 10, count(c.Cache), name)
+		c.Close(context.Background())
+	}
+}
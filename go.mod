@@ -1,12 +1,11 @@
 module github.com/almamedia/go-cache-lib
 
-go 1.15
+go 1.21
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/google/uuid v1.2.0
 	github.com/julienschmidt/httprouter v1.2.0
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/streamrail/concurrent-map v0.0.0-20160823150647-8bf1e9bacbf6
 	github.com/stretchr/testify v1.2.2
 )
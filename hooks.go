@@ -0,0 +1,230 @@
+package gocachelib
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Reason why an event happened
+type Reason string
+
+const (
+	// ReasonNew item was inserted for a key not cached
+	ReasonNew Reason = "new"
+	// ReasonReplaced item replaced the one cached for the key
+	ReasonReplaced Reason = "replaced"
+	// ReasonFull item was evicted to make room
+	ReasonFull Reason = "full"
+	// ReasonTooLarge refreshed item was evicted for not fitting in cache
+	ReasonTooLarge Reason = "too large"
+	// ReasonUnused item was revoked for not being used within its TTL
+	ReasonUnused Reason = "unused"
+	// ReasonStale item was dropped for being staler than its bound
+	ReasonStale Reason = "stale"
+	// ReasonDeleted item was deleted or purged
+	ReasonDeleted Reason = "deleted"
+	// ReasonLoaded item was refreshed with a new value
+	ReasonLoaded Reason = "loaded"
+	// ReasonUnchanged item was refreshed keeping its value, GetFunc returned no new one
+	ReasonUnchanged Reason = "unchanged"
+	// ReasonNotModified item was refreshed keeping its value, the origin told it had not changed
+	ReasonNotModified Reason = "not modified"
+	// ReasonNotFound item was found missing at the origin when refreshing it
+	ReasonNotFound Reason = "not found"
+	// ReasonRetry refresh failed and is retried later
+	ReasonRetry Reason = "retry"
+	// ReasonGaveUp refresh failed and refreshing was given up
+	ReasonGaveUp Reason = "gave up"
+)
+
+// Event of a cached item passed to hooks
+// Key of the item
+// Reason why the event happened
+// Old metadata of the item before the event, nil if there was no item
+// OldValue before the event
+// New metadata of the item after the event, nil if item was removed
+// NewValue after the event
+// Err of a failed refresh
+type Event[K comparable, V any] struct {
+	Key      K
+	Reason   Reason
+	Old      *EntryInfo
+	OldValue V
+	New      *EntryInfo
+	NewValue V
+	Err      error
+}
+
+type hookKind int
+
+const (
+	hookInsert hookKind = iota
+	hookEvict
+	hookRevoke
+	hookRefresh
+	hookRefreshError
+	hookKinds
+)
+
+// hooks registered by kind, and events waiting to be passed to them
+type hooks[K comparable, V any] struct {
+	mu       sync.RWMutex
+	handlers [hookKinds][]func(Event[K, V])
+
+	// events are passed to hooks in order by a dispatcher goroutine, started on the first event.
+	// At most size events wait for it, the rest are dropped and counted in dropped.
+	queueMu  sync.Mutex
+	queue    []pendingEvent[K, V]
+	size     int
+	dropped  *atomic.Uint64
+	nonEmpty *sync.Cond
+	running  bool
+	closed   bool
+}
+
+// event for hooks of kind, or a callback to run in order with events
+type pendingEvent[K comparable, V any] struct {
+	kind     hookKind
+	event    Event[K, V]
+	callback func()
+}
+
+func (h *hooks[K, V]) register(kind hookKind, f func(Event[K, V])) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[kind] = append(h.handlers[kind], f)
+}
+
+func (h *hooks[K, V]) registered(kind hookKind) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.handlers[kind]) > 0
+}
+
+func (h *hooks[K, V]) call(kind hookKind, event Event[K, V]) {
+	h.mu.RLock()
+	handlers := h.handlers[kind]
+	h.mu.RUnlock()
+	for _, f := range handlers {
+		f(event)
+	}
+}
+
+// queue event for the dispatcher without waiting for hooks, dropping it if queue is full
+func (h *hooks[K, V]) enqueue(pe pendingEvent[K, V]) {
+	h.queueMu.Lock()
+	defer h.queueMu.Unlock()
+	if h.closed {
+		return
+	}
+	if len(h.queue) >= h.size {
+		h.dropped.Add(1)
+		return
+	}
+	h.push(pe)
+}
+
+// queue callback for the dispatcher. Callbacks hold no values and are never dropped.
+func (h *hooks[K, V]) later(f func()) {
+	h.queueMu.Lock()
+	defer h.queueMu.Unlock()
+	if !h.closed {
+		h.push(pendingEvent[K, V]{callback: f})
+	}
+}
+
+// append to queue, starting the dispatcher if needed. queueMu must be held.
+func (h *hooks[K, V]) push(pe pendingEvent[K, V]) {
+	if !h.running {
+		h.running = true
+		h.nonEmpty = sync.NewCond(&h.queueMu)
+		go h.dispatch()
+	}
+	h.queue = append(h.queue, pe)
+	h.nonEmpty.Signal()
+}
+
+// pass queued events to hooks until closed and the queue is drained
+func (h *hooks[K, V]) dispatch() {
+	for {
+		h.queueMu.Lock()
+		for len(h.queue) == 0 && !h.closed {
+			h.nonEmpty.Wait()
+		}
+		events := h.queue
+		h.queue = nil
+		h.queueMu.Unlock()
+		if len(events) == 0 {
+			return
+		}
+		for _, pe := range events {
+			if pe.callback != nil {
+				pe.callback()
+				continue
+			}
+			h.call(pe.kind, pe.event)
+		}
+	}
+}
+
+// stop dispatcher once events queued so far are passed to hooks
+func (h *hooks[K, V]) close() {
+	h.queueMu.Lock()
+	defer h.queueMu.Unlock()
+	h.closed = true
+	if h.running {
+		h.nonEmpty.Signal()
+	}
+}
+
+// OnInsert registers a hook called when an item is set, including keys known to be missing.
+// Hooks falling behind steady inserts miss events, see WithHookBuffer.
+func (c *Cache[K, V]) OnInsert(f func(Event[K, V])) {
+	c.hooks.register(hookInsert, f)
+}
+
+// OnEvict registers a hook called when an item is removed to make room.
+// Hooks falling behind steady evictions miss events, see WithHookBuffer.
+func (c *Cache[K, V]) OnEvict(f func(Event[K, V])) {
+	c.hooks.register(hookEvict, f)
+}
+
+// OnRevoke registers a hook called when an item is revoked after its TTL, deleted or purged
+func (c *Cache[K, V]) OnRevoke(f func(Event[K, V])) {
+	c.hooks.register(hookRevoke, f)
+}
+
+// OnRefresh registers a hook called when an item is refreshed in the background
+func (c *Cache[K, V]) OnRefresh(f func(Event[K, V])) {
+	c.hooks.register(hookRefresh, f)
+}
+
+// OnRefreshError registers a hook called when refreshing an item in the background fails
+func (c *Cache[K, V]) OnRefreshError(f func(Event[K, V])) {
+	c.hooks.register(hookRefreshError, f)
+}
+
+// queue event for hooks of kind. Hooks are called one at a time in order of events on a goroutine
+// of their own, so that slow ones stall neither callers nor background loops and workers. Events
+// hold on to values, so hooks falling WithHookBuffer events behind miss events instead.
+func (c *Cache[K, V]) emit(kind hookKind, event Event[K, V]) {
+	if c.hooks.registered(kind) {
+		c.hooks.enqueue(pendingEvent[K, V]{kind: kind, event: event})
+	}
+}
+
+// event of entry e replacing old, either may be nil
+func eventOf[K comparable, V any](key K, reason Reason, old, e *entry[K, V]) Event[K, V] {
+	event := Event[K, V]{Key: key, Reason: reason}
+	if old != nil {
+		info := old.info()
+		event.Old = &info
+		event.OldValue = old.Value
+	}
+	if e != nil {
+		info := e.info()
+		event.New = &info
+		event.NewValue = e.Value
+	}
+	return event
+}
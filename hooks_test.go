@@ -0,0 +1,203 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder of events passed to hooks
+type recorder struct {
+	mu     sync.Mutex
+	events map[string][]Event[string, []byte]
+}
+
+func record(c *BytesCache) *recorder {
+	r := &recorder{events: map[string][]Event[string, []byte]{}}
+	hook := func(name string) func(Event[string, []byte]) {
+		return func(e Event[string, []byte]) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.events[name] = append(r.events[name], e)
+		}
+	}
+	c.OnInsert(hook("insert"))
+	c.OnEvict(hook("evict"))
+	c.OnRevoke(hook("revoke"))
+	c.OnRefresh(hook("refresh"))
+	c.OnRefreshError(hook("refreshError"))
+	return r
+}
+
+func (r *recorder) get(name string) []Event[string, []byte] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event[string, []byte](nil), r.events[name]...)
+}
+
+// wait until n events have been passed to hook name and return them
+func (r *recorder) wait(t *testing.T, name string, n int) []Event[string, []byte] {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.get(name)) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Hook %s should have got %d events, got %d", name, n, len(r.get(name)))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return r.get(name)
+}
+
+func TestInsertAndEvictHooks(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithMaxEntries(1))
+	c.Start()
+	defer c.Close(context.Background())
+	r := record(c)
+	c.AddItem(CacheItem{Key: "a", Value: []byte("1")})
+	c.AddItem(CacheItem{Key: "a", Value: []byte("2")})
+	c.AddItem(CacheItem{Key: "b", Value: []byte("3")})
+	inserts := r.wait(t, "insert", 3)
+	assert.Len(t, inserts, 3)
+	assert.Equal(t, ReasonNew, inserts[0].Reason)
+	assert.Nil(t, inserts[0].Old)
+	assert.Equal(t, "1", string(inserts[0].NewValue))
+	assert.Equal(t, ReasonReplaced, inserts[1].Reason)
+	assert.Equal(t, "1", string(inserts[1].OldValue))
+	assert.NotNil(t, inserts[1].Old)
+	assert.Equal(t, "2", string(inserts[1].NewValue))
+	evicts := r.wait(t, "evict", 1)
+	assert.Len(t, evicts, 1)
+	assert.Equal(t, "a", evicts[0].Key)
+	assert.Equal(t, ReasonFull, evicts[0].Reason)
+	assert.Equal(t, "2", string(evicts[0].OldValue))
+	assert.Nil(t, evicts[0].New)
+}
+
+func TestRevokeHookRunsWithoutLocks(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithLoopInterval(5*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
+	revoked := make(chan Event[string, []byte], 1)
+	c.OnRevoke(func(e Event[string, []byte]) {
+		// hooks may use the cache
+		c.AddItem(CacheItem{Key: "derived", Value: []byte("cleaned")})
+		c.loopMutex.Lock()
+		c.loopMutex.Unlock()
+		revoked <- e
+	})
+	c.AddItem(CacheItem{Key: "key", Value: []byte("value"), TTL: 1 * time.Millisecond, Expiration: 1 * time.Millisecond})
+	select {
+	case e := <-revoked:
+		assert.Equal(t, "key", e.Key)
+		assert.Equal(t, ReasonUnused, e.Reason)
+		assert.Equal(t, "value", string(e.OldValue))
+	case <-time.After(1 * time.Second):
+		t.Fatal("Revoke hook should have been called")
+	}
+}
+
+func TestRefreshHooks(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithLoopInterval(5*time.Millisecond), WithRefreshLeadTime(0), WithRetryPolicy(RetryPolicy{InitialInterval: 5 * time.Millisecond, MaxInterval: 5 * time.Millisecond, Multiplier: 1, MaxAttempts: 2}))
+	c.Start()
+	defer c.Close(context.Background())
+	r := record(c)
+	originErr := errors.New("origin down")
+	c.AddItem(CacheItem{Key: "ok", Value: []byte("old"), Expiration: 10 * time.Millisecond, GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("new"), nil
+	}})
+	c.AddItem(CacheItem{Key: "failing", Value: []byte("old"), Expiration: 10 * time.Millisecond, GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+		return nil, originErr
+	}})
+	c.AddItem(CacheItem{Key: "gone", Value: []byte("old"), Expiration: 10 * time.Millisecond, GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}})
+	errs := r.wait(t, "refreshError", 2)
+	r.wait(t, "refresh", 2)
+	refreshes := map[string]Event[string, []byte]{}
+	for _, e := range r.get("refresh") {
+		if _, ok := refreshes[e.Key]; !ok {
+			refreshes[e.Key] = e
+		}
+	}
+	assert.Equal(t, ReasonLoaded, refreshes["ok"].Reason)
+	assert.Equal(t, "old", string(refreshes["ok"].OldValue))
+	assert.Equal(t, "new", string(refreshes["ok"].NewValue))
+	assert.Equal(t, ReasonNotFound, refreshes["gone"].Reason)
+	assert.True(t, refreshes["gone"].New.NotFound)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, ReasonRetry, errs[0].Reason)
+	assert.Equal(t, originErr, errs[0].Err)
+	assert.Equal(t, 0, errs[0].Old.Failures)
+	assert.Equal(t, 1, errs[0].New.Failures)
+	assert.Equal(t, ReasonGaveUp, errs[1].Reason)
+}
+
+func TestSlowHooksDoNotStallCache(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(5*time.Millisecond), WithRefreshLeadTime(0))
+	c.Start()
+	defer c.Close(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	c.OnRevoke(func(e Event[string, []byte]) {
+		<-release
+	})
+	c.OnRefresh(func(e Event[string, []byte]) {
+		<-release
+	})
+	var refreshes int32
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		atomic.AddInt32(&refreshes, 1)
+		return []byte("new"), nil
+	}
+	c.AddItem(CacheItem{Key: "a", Value: []byte("old"), TTL: 1 * time.Millisecond, Expiration: 1 * time.Millisecond})
+	c.AddItem(CacheItem{Key: "b", Value: []byte("old"), TTL: 1 * time.Millisecond, Expiration: 1 * time.Millisecond})
+	c.AddItem(CacheItem{Key: "c", Value: []byte("old"), Expiration: 10 * time.Millisecond, GetFunc: loader})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, a := c.Inspect("a")
+		_, b := c.Inspect("b")
+		if !a && !b && atomic.LoadInt32(&refreshes) >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Blocked hooks should not stop revoking and refreshing items")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHookBufferDropsEvents(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithHookBuffer(2), WithMaxEntries(100))
+	c.Start()
+	defer c.Close(context.Background())
+	release := make(chan struct{})
+	var inserts int32
+	c.OnInsert(func(e Event[string, []byte]) {
+		<-release
+		atomic.AddInt32(&inserts, 1)
+	})
+	for i := 0; i < 10; i++ {
+		c.AddItem(CacheItem{Key: string(rune('a' + i)), Value: []byte("value")})
+	}
+	dropped := c.Stats().DroppedEvents
+	assert.True(t, dropped > 0, "Events should be dropped while hooks are behind")
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for uint64(atomic.LoadInt32(&inserts))+dropped < 10 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Events not dropped should be passed to hooks, got %d of %d", atomic.LoadInt32(&inserts), 10-dropped)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, dropped, c.Stats().DroppedEvents)
+}
@@ -0,0 +1,98 @@
+package gocachelib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPLoader returns a loader fetching bodies of URLs used as keys with client, http.DefaultClient
+// if nil. ETag and Last-Modified of the response are kept with the item and sent back as
+// If-None-Match and If-Modified-Since when refreshing it, so that a 304 Not Modified response keeps
+// the cached body without transferring it again. Items not setting their own expiration expire as
+// told by Cache-Control max-age or Expires of the origin, and those told to be stale already, for
+// example with Cache-Control no-cache or max-age=0, are revalidated on the next refresh loop.
+// 404 and 410 are reported as ErrNotFound, other statuses than 200 and 304 as errors.
+func HTTPLoader(client *http.Client) Loader[string, []byte] {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context, url string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		state, ok := LoadStateFrom(ctx)
+		if !ok {
+			state = &LoadState{}
+		}
+		if state.ETag != "" {
+			req.Header.Set("If-None-Match", state.ETag)
+		}
+		if state.LastModified != "" {
+			req.Header.Set("If-Modified-Since", state.LastModified)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		switch res.StatusCode {
+		case http.StatusOK:
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				return nil, err
+			}
+			state.ETag = res.Header.Get("ETag")
+			state.LastModified = res.Header.Get("Last-Modified")
+			lifetime, told := freshness(res.Header, time.Now())
+			state.Expiration, state.Expired = lifetime, told && lifetime == 0
+			return body, nil
+		case http.StatusNotModified:
+			// 304 may update freshness, otherwise the earlier one stands
+			if lifetime, told := freshness(res.Header, time.Now()); told {
+				state.Expiration, state.Expired = lifetime, lifetime == 0
+			}
+			return nil, ErrNotModified
+		case http.StatusNotFound, http.StatusGone:
+			return nil, fmt.Errorf("%w: GET %s: %s", ErrNotFound, url, res.Status)
+		}
+		return nil, fmt.Errorf("GET %s: unexpected status %s", url, res.Status)
+	}
+}
+
+// freshness lifetime of response left, and whether the origin told it. Zero lifetime that was told
+// means the response is stale already: no-cache, no-store, max-age=0, invalid max-age or Expires, or
+// Age beyond lifetime. Cache-Control max-age takes precedence over Expires, RFC 9111 section 4.2.1.
+func freshness(h http.Header, now time.Time) (time.Duration, bool) {
+	if hasDirective(h, "no-cache") || hasDirective(h, "no-store") {
+		return 0, true
+	}
+	var lifetime time.Duration
+	if value, ok := directiveValue(h, "max-age"); ok {
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, true
+		}
+		lifetime = time.Duration(seconds) * time.Second
+	} else if expires := h.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return 0, true
+		}
+		date, err := http.ParseTime(h.Get("Date"))
+		if err != nil {
+			date = now
+		}
+		lifetime = expiresAt.Sub(date)
+	} else {
+		return 0, false
+	}
+	if age, err := strconv.Atoi(h.Get("Age")); err == nil {
+		lifetime -= time.Duration(age) * time.Second
+	}
+	return max(lifetime, 0), true
+}
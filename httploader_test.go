@@ -0,0 +1,156 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPLoaderRevalidates(t *testing.T) {
+	t.Parallel()
+	var full, notModified atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") == "Mon, 02 Jan 2006 15:04:05 GMT" {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte("hello"))
+	}))
+	defer origin.Close()
+	c := mustNew(t, WithDefaultExpiration(50*time.Millisecond), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
+	refreshed := make(chan Event[string, []byte], 10)
+	c.OnRefresh(func(e Event[string, []byte]) {
+		refreshed <- e
+	})
+
+	value, err := c.GetOrLoad(context.Background(), origin.URL, HTTPLoader(origin.Client()))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(value))
+	select {
+	case e := <-refreshed:
+		assert.Equal(t, ReasonNotModified, e.Reason)
+		assert.True(t, e.New.ExpireTime.After(e.Old.ExpireTime))
+		assert.Equal(t, "hello", string(e.NewValue))
+	case <-time.After(5 * time.Second):
+		t.Fatal("item was not refreshed")
+	}
+	assert.Equal(t, int32(1), full.Load())
+	assert.True(t, notModified.Load() >= 1)
+	assert.Equal(t, uint64(0), c.Stats().RefreshFailures)
+}
+
+func TestHTTPLoaderExpirationFromOrigin(t *testing.T) {
+	t.Parallel()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		w.Write([]byte("hello"))
+	}))
+	defer origin.Close()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	loader := HTTPLoader(origin.Client())
+
+	_, err := c.GetOrLoad(context.Background(), origin.URL+"/a", loader)
+	assert.NoError(t, err)
+	info, _ := c.Inspect(origin.URL + "/a")
+	assert.WithinDuration(t, time.Now().Add(600*time.Second), info.ExpireTime, 5*time.Second)
+
+	// expiration of the item wins over that of the origin
+	c.Set(Item[string, []byte]{Key: origin.URL + "/b", Expiration: 1 * time.Minute, Loader: loader})
+	info, _ = c.Inspect(origin.URL + "/b")
+	assert.WithinDuration(t, time.Now().Add(1*time.Minute), info.ExpireTime, 5*time.Second)
+}
+
+func TestHTTPLoaderRevalidatesStaleResponses(t *testing.T) {
+	t.Parallel()
+	var requests atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "no-cache, max-age=0")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer origin.Close()
+	c := mustNew(t, WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
+
+	_, err := c.GetOrLoad(context.Background(), origin.URL, HTTPLoader(origin.Client()))
+	assert.NoError(t, err)
+	info, _ := c.Inspect(origin.URL)
+	assert.False(t, info.ExpireTime.After(time.Now()), "Response stale at the origin should expire at once, expires at %v", info.ExpireTime)
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("Stale response should be revalidated on every loop")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, "hello", string(c.GetValue(origin.URL)))
+}
+
+func TestHTTPLoaderErrors(t *testing.T) {
+	t.Parallel()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer origin.Close()
+	loader := HTTPLoader(origin.Client())
+
+	_, err := loader(context.Background(), origin.URL+"/gone")
+	assert.True(t, errors.Is(err, ErrNotFound), "should have got ErrNotFound, got %v", err)
+	_, err = loader(context.Background(), origin.URL+"/error")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestFreshness(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Add(-1 * time.Minute).Format(http.TimeFormat)
+	tests := map[string]struct {
+		header http.Header
+		want   time.Duration
+		told   bool
+	}{
+		"none":                 {http.Header{}, 0, false},
+		"max-age":              {http.Header{"Cache-Control": {"public, max-age=60"}}, 60 * time.Second, true},
+		"max-age with age":     {http.Header{"Cache-Control": {"max-age=60"}, "Age": {"20"}}, 40 * time.Second, true},
+		"max-age over expires": {http.Header{"Cache-Control": {"max-age=60"}, "Expires": {now.Add(1 * time.Hour).Format(http.TimeFormat)}}, 60 * time.Second, true},
+		"max-age zero":         {http.Header{"Cache-Control": {"max-age=0"}}, 0, true},
+		"no-cache":             {http.Header{"Cache-Control": {"no-cache, max-age=60"}}, 0, true},
+		"no-store":             {http.Header{"Cache-Control": {"no-store"}}, 0, true},
+		"invalid max-age":      {http.Header{"Cache-Control": {"max-age=soon"}}, 0, true},
+		"expires from date":    {http.Header{"Expires": {now.Add(1 * time.Hour).Format(http.TimeFormat)}, "Date": {date}}, 61 * time.Minute, true},
+		"expires from now":     {http.Header{"Expires": {now.Add(1 * time.Hour).Format(http.TimeFormat)}}, 1 * time.Hour, true},
+		"expired":              {http.Header{"Expires": {"0"}}, 0, true},
+		"stale":                {http.Header{"Cache-Control": {"max-age=60"}, "Age": {"90"}}, 0, true},
+	}
+	for name, test := range tests {
+		lifetime, told := freshness(test.header, now)
+		assert.Equal(t, test.want, lifetime, name)
+		assert.Equal(t, test.told, told, name)
+	}
+}
@@ -0,0 +1,108 @@
+package gocachelib
+
+import (
+	"container/heap"
+	"time"
+)
+
+// entries ordered by time, so that loops only touch due items. Position of each entry is kept
+// in the entry itself, plus one so that zero means not in heap.
+type timeIndex[K comparable, V any] struct {
+	entries []*entry[K, V]
+	at      func(e *entry[K, V]) time.Time
+	pos     func(e *entry[K, V]) *int
+}
+
+func (h *timeIndex[K, V]) Len() int { return len(h.entries) }
+
+func (h *timeIndex[K, V]) Less(i, j int) bool {
+	return h.at(h.entries[i]).Before(h.at(h.entries[j]))
+}
+
+func (h *timeIndex[K, V]) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	*h.pos(h.entries[i]) = i + 1
+	*h.pos(h.entries[j]) = j + 1
+}
+
+func (h *timeIndex[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	h.entries = append(h.entries, e)
+	*h.pos(e) = len(h.entries)
+}
+
+func (h *timeIndex[K, V]) Pop() any {
+	last := len(h.entries) - 1
+	e := h.entries[last]
+	h.entries[last] = nil
+	h.entries = h.entries[:last]
+	*h.pos(e) = 0
+	return e
+}
+
+// add entry or move it to its place after its time changed
+func (h *timeIndex[K, V]) fix(e *entry[K, V]) {
+	if p := *h.pos(e); p > 0 {
+		heap.Fix(h, p-1)
+		return
+	}
+	heap.Push(h, e)
+}
+
+func (h *timeIndex[K, V]) remove(e *entry[K, V]) {
+	if p := *h.pos(e); p > 0 {
+		heap.Remove(h, p-1)
+	}
+}
+
+// earliest entry, nil if none
+func (h *timeIndex[K, V]) peek() *entry[K, V] {
+	if len(h.entries) == 0 {
+		return nil
+	}
+	return h.entries[0]
+}
+
+func (h *timeIndex[K, V]) pop() *entry[K, V] {
+	return heap.Pop(h).(*entry[K, V])
+}
+
+func newRefreshIndex[K comparable, V any](leadTime time.Duration) *timeIndex[K, V] {
+	return &timeIndex[K, V]{
+		at:  func(e *entry[K, V]) time.Time { return e.refreshAt(leadTime) },
+		pos: func(e *entry[K, V]) *int { return &e.refreshPos },
+	}
+}
+
+func newRevokeIndex[K comparable, V any]() *timeIndex[K, V] {
+	return &timeIndex[K, V]{
+		at:  func(e *entry[K, V]) time.Time { return e.RevokeTime },
+		pos: func(e *entry[K, V]) *int { return &e.revokePos },
+	}
+}
+
+// when entry is due for refresh, once it expires soon and is not backing off
+func (e *entry[K, V]) refreshAt(leadTime time.Duration) time.Time {
+	at := e.ExpireTime.Add(-leadTime - e.earlyBy)
+	if e.RetryTime.After(at) {
+		return e.RetryTime
+	}
+	return at
+}
+
+// index entry by its current times, mu must be held. Only entries that can be refreshed
+// and are not being refreshed are indexed for refresh.
+func (c *Cache[K, V]) schedule(e *entry[K, V]) {
+	c.revokes.fix(e)
+	if e.Loader != nil && !e.Updating && !e.GaveUp && !e.NotFound {
+		c.refreshes.fix(e)
+	} else {
+		c.refreshes.remove(e)
+	}
+}
+
+// remove entry from indexes, mu must be held
+func (c *Cache[K, V]) unschedule(e *entry[K, V]) {
+	c.revokes.remove(e)
+	c.refreshes.remove(e)
+}
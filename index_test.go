@@ -0,0 +1,60 @@
+package gocachelib
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeIndex(t *testing.T) {
+	t.Parallel()
+	h := newRevokeIndex[string, []byte]()
+	now := time.Now()
+	entries := map[string]*entry[string, []byte]{}
+	for i, offset := range []int{5, 1, 4, 2, 3} {
+		e := &entry[string, []byte]{Item: Item[string, []byte]{Key: fmt.Sprint(i)}, RevokeTime: now.Add(time.Duration(offset) * time.Second)}
+		entries[e.Key] = e
+		h.fix(e)
+	}
+	assert.Equal(t, "1", h.peek().Key)
+	entries["0"].RevokeTime = now
+	h.fix(entries["0"])
+	assert.Equal(t, "0", h.peek().Key, "Moved entry should be fixed to its place")
+	h.remove(entries["3"])
+	assert.Equal(t, 0, entries["3"].revokePos)
+	var order []string
+	for h.peek() != nil {
+		order = append(order, h.pop().Key)
+	}
+	assert.Equal(t, []string{"0", "1", "4", "2"}, order)
+}
+
+func TestIndexesFollowItems(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithMaxEntries(50), WithLoopInterval(5*time.Millisecond), WithRefreshLeadTime(0))
+	c.Start()
+	defer c.Close(context.Background())
+	for i := 0; i < 100; i++ {
+		item := CacheItem{Key: fmt.Sprint(i), Value: []byte("value"), Expiration: 10 * time.Millisecond, GetFunc: noopGetFunc}
+		if i%3 == 0 {
+			item.TTL = 20 * time.Millisecond
+			item.GetFunc = nil
+		}
+		c.AddItem(item)
+	}
+	time.Sleep(50 * time.Millisecond)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Equal(t, len(c.items), c.revokes.Len(), "Every item should be indexed for revocation")
+	for _, e := range c.revokes.entries {
+		assert.Equal(t, e, c.items[e.Key])
+		assert.False(t, time.Now().After(e.RevokeTime.Add(25*time.Millisecond)), "Revoke loop should have removed %v", e.Key)
+	}
+	for _, e := range c.refreshes.entries {
+		assert.Equal(t, e, c.items[e.Key])
+		assert.NotNil(t, e.Loader)
+	}
+}
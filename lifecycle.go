@@ -0,0 +1,119 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// State of cache lifecycle. Caches begin as StateNew, run between Start and Close and cannot be restarted.
+type State int32
+
+const (
+	// StateNew cache has been created but not started
+	StateNew State = iota
+	// StateRunning cache is started and accepts items
+	StateRunning
+	// StateStopping cache is being closed
+	StateStopping
+	// StateStopped cache has been closed
+	StateStopped
+)
+
+func (s State) String() string {
+	switch s {
+	case StateNew:
+		return "new"
+	case StateRunning:
+		return "running"
+	case StateStopping:
+		return "stopping"
+	case StateStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+// ErrNotRunning is returned when cache is used before Start or after Close
+var ErrNotRunning = errors.New("cache is not running")
+
+// ErrAlreadyStarted is returned when Start is called on a running cache
+var ErrAlreadyStarted = errors.New("cache is already started")
+
+// ErrClosed is returned when Start or Close is called on a closed cache
+var ErrClosed = errors.New("cache is closed")
+
+// CloseReport tells which refresh jobs were given up when cache was closed
+type CloseReport[K comparable] struct {
+	// Dropped keys were queued for refresh but never started
+	Dropped []K
+	// Abandoned keys were still being refreshed when the context ended
+	Abandoned []K
+}
+
+// State of cache lifecycle
+func (c *Cache[K, V]) State() State {
+	return State(atomic.LoadInt32(&c.state))
+}
+
+func (c *Cache[K, V]) transition(from, to State) bool {
+	return atomic.CompareAndSwapInt32(&c.state, int32(from), int32(to))
+}
+
+// Close stops background loops, drops queued refresh jobs and waits for in-flight loader calls
+// until ctx is done, after which contexts of the loaders still running are cancelled. Keys whose
+// refresh was given up are listed in the report, and if ctx ended before workers finished its
+// error is returned. Closing a cache that was never started just
+// marks it stopped. Closed cache returns no values and releases its items.
+func (c *Cache[K, V]) Close(ctx context.Context) (CloseReport[K], error) {
+	var report CloseReport[K]
+	if c.transition(StateNew, StateStopped) {
+		c.hooks.close()
+		return report, nil
+	}
+	if !c.transition(StateRunning, StateStopping) {
+		return report, ErrClosed
+	}
+	c.cfg.logger.Info("Stopping in-memory cache background processing")
+	c.loopMutex.Lock()
+	close(c.stopLoops)
+	report.Dropped = c.queue.close()
+	c.loopMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.workerWg.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		report.Abandoned = c.inFlightKeys()
+		err = ctx.Err()
+	}
+	// loaders still running are told to give up
+	c.cancel()
+	if len(report.Dropped) > 0 || len(report.Abandoned) > 0 {
+		c.cfg.logger.Warn("Closed cache giving up refreshes", "dropped", len(report.Dropped), "abandoned", len(report.Abandoned))
+	}
+	// closed cache returns no values, let them go
+	c.mu.Lock()
+	for _, e := range c.items {
+		c.remove(e)
+	}
+	c.mu.Unlock()
+	c.hooks.close()
+	atomic.StoreInt32(&c.state, int32(StateStopped))
+	return report, err
+}
+
+func (c *Cache[K, V]) inFlightKeys() []K {
+	c.inFlightMutex.Lock()
+	defer c.inFlightMutex.Unlock()
+	keys := make([]K, 0, len(c.inFlight))
+	for k := range c.inFlight {
+		keys = append(keys, k)
+	}
+	return keys
+}
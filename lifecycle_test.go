@@ -0,0 +1,134 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycle(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	item := CacheItem{
+		Key:        "TestLifecycle",
+		Value:      []byte("TestLifecycle"),
+		Expiration: 1 * time.Second,
+		GetFunc:    noopGetFunc,
+	}
+	assert.Equal(t, StateNew, c.State())
+	assert.Equal(t, ErrNotRunning, c.AddItem(item), "Items should not be accepted before Start")
+	assert.Nil(t, c.GetValue("TestLifecycle"))
+
+	assert.NoError(t, c.Start())
+	assert.Equal(t, StateRunning, c.State())
+	assert.Equal(t, ErrAlreadyStarted, c.Start(), "Cache should not be started twice")
+	assert.NoError(t, c.AddItem(item))
+	assert.Equal(t, "TestLifecycle", string(c.GetValue("TestLifecycle")))
+
+	report, err := c.Close(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, report.Dropped)
+	assert.Empty(t, report.Abandoned)
+	assert.Equal(t, StateStopped, c.State())
+	assert.Equal(t, ErrNotRunning, c.AddItem(item), "Items should not be accepted after Close")
+	assert.Nil(t, c.GetValue("TestLifecycle"), "Closed cache should not return values")
+	assert.Empty(t, c.Keys(), "Closed cache should release its items")
+	assert.Equal(t, int64(0), c.Bytes())
+	assert.Equal(t, ErrClosed, c.Start(), "Closed cache should not be restarted")
+	_, err = c.Close(context.Background())
+	assert.Equal(t, ErrClosed, err, "Cache should not be closed twice")
+}
+
+func TestCloseNotStarted(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	_, err := c.Close(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, StateStopped, c.State())
+	assert.Equal(t, ErrClosed, c.Start())
+}
+
+func TestCloseReportsDroppedAndAbandonedRefreshes(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithQueueSize(10), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	release := make(chan struct{})
+	defer close(release)
+	blockingGetFunc := func(ctx context.Context, key string) ([]byte, error) {
+		<-release
+		return nil, nil
+	}
+	keys := []string{"TestCloseReports1", "TestCloseReports2", "TestCloseReports3"}
+	for _, key := range keys {
+		c.AddItem(CacheItem{
+			Key:        key,
+			Value:      []byte(key),
+			Expiration: 1 * time.Millisecond,
+			GetFunc:    blockingGetFunc,
+		})
+	}
+	// let refresh loop queue all items, the only worker blocks on the first one
+	time.Sleep(30 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err := c.Close(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "Close should time out waiting for blocked worker, got %v", err)
+	assert.Len(t, report.Abandoned, 1)
+	assert.Len(t, report.Dropped, 2)
+	got := append(report.Abandoned, report.Dropped...)
+	sort.Strings(got)
+	assert.Equal(t, keys, got)
+	assert.Equal(t, StateStopped, c.State())
+}
+
+func TestCloseCancelsRunningLoaders(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	loaderErr := make(chan error, 1)
+	c.AddItem(CacheItem{
+		Key:        "TestCloseCancelsRunningLoaders",
+		Value:      []byte("TestCloseCancelsRunningLoaders"),
+		Expiration: 1 * time.Millisecond,
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			<-ctx.Done()
+			loaderErr <- ctx.Err()
+			return nil, ctx.Err()
+		},
+	})
+	time.Sleep(30 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report, err := c.Close(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, []string{"TestCloseCancelsRunningLoaders"}, report.Abandoned)
+	select {
+	case err := <-loaderErr:
+		assert.Equal(t, context.Canceled, err, "Loader context should be cancelled by Close")
+	case <-time.After(1 * time.Second):
+		t.Error("Loader context should have been cancelled by Close")
+	}
+}
+
+// not parallel, counting goroutines of closed caches
+func TestCloseStopsLoops(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		c := mustNew(t, WithWorkers(1))
+		assert.NoError(t, c.Start())
+		_, err := c.Close(context.Background())
+		assert.NoError(t, err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before+5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Closed caches should not leave goroutines running, %d before and %d after", before, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
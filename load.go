@@ -0,0 +1,96 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// load in progress, shared by callers missing on the same key
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// ErrLoaderPanic is returned by GetOrLoad when the loader panicked
+var ErrLoaderPanic = errors.New("loader panicked")
+
+// GetOrLoad returns the value for key from cache, or loads it with loader on a miss and adds it
+// to cache with the expiration given by the loader in its LoadState or the default one, and the
+// default TTL. The loader is also used to refresh the item later on. When many callers miss on the
+// same key at once only one load runs and all of them get its result. The load gets values of the
+// context of the caller that started it, but is not cancelled when that caller gives up, only when
+// the refresh timeout of the cache passes. Each caller gives up waiting when its own context ends.
+// Failed loads are not cached, except ErrNotFound which is remembered for the negative TTL and
+// returned until then. A panicking loader is reported as ErrLoaderPanic.
+// ErrNotRunning is returned if cache has not been started or is closed.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, loader Loader[K, V]) (V, error) {
+	var zero V
+	if value, status := c.Lookup(key); status != LookupMiss {
+		return value, lookupErr(status)
+	}
+	if c.State() != StateRunning {
+		return zero, ErrNotRunning
+	}
+	c.callsMutex.Lock()
+	// someone may have loaded the value while we were waiting for the lock
+	if value, status := c.lookup(key); status != LookupMiss {
+		c.callsMutex.Unlock()
+		return value, lookupErr(status)
+	}
+	cl, ok := c.calls[key]
+	if !ok {
+		cl = &call[V]{done: make(chan struct{})}
+		c.calls[key] = cl
+		go c.loadShared(context.WithoutCancel(ctx), key, loader, cl)
+	}
+	c.callsMutex.Unlock()
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// load value for callers of GetOrLoad waiting on cl and add it to cache
+func (c *Cache[K, V]) loadShared(ctx context.Context, key K, loader Loader[K, V], cl *call[V]) {
+	defer func() {
+		if r := recover(); r != nil {
+			var zero V
+			cl.value, cl.err = zero, fmt.Errorf("%w: %v", ErrLoaderPanic, r)
+			c.cfg.logger.Error("Loading cache item failed", "key", key, "reason", "panic", "error", r)
+		}
+		c.callsMutex.Lock()
+		delete(c.calls, key)
+		c.callsMutex.Unlock()
+		close(cl.done)
+	}()
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.refreshTimeout)
+	defer cancel()
+	var zero V
+	var state LoadState
+	start := time.Now()
+	cl.value, cl.err = loader(withLoadState(ctx, &state), key)
+	c.counters.recordLoad(time.Since(start))
+	switch {
+	case cl.err == nil:
+		if err := c.set(Item[K, V]{Key: key, Value: cl.value, Loader: loader}, state); err != nil {
+			cl.value, cl.err = zero, err
+		}
+	case errors.Is(cl.err, ErrNotFound):
+		cl.value = zero
+		if err := c.SetNotFound(key); err != nil {
+			cl.err = err
+		}
+	}
+}
+
+func lookupErr(status LookupStatus) error {
+	if status == LookupNotFound {
+		return ErrNotFound
+	}
+	return nil
+}
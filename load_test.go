@@ -0,0 +1,166 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrLoadCoalescesConcurrentMisses(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithDefaultExpiration(1*time.Minute))
+	c.Start()
+	defer c.Close(context.Background())
+	var loads int32
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(20 * time.Millisecond)
+		return []byte(key), nil
+	}
+	key := "TestGetOrLoadCoalescesConcurrentMisses"
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.GetOrLoad(context.Background(), key, loader)
+			assert.NoError(t, err)
+			assert.Equal(t, key, string(value))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads), "Concurrent misses should share one load")
+	assert.Equal(t, key, string(c.GetValue(key)), "Loaded value should have been added to cache")
+	e, _ := peek(c.Cache, key)
+	assert.Equal(t, 1*time.Minute, e.Expiration, "Loaded item should get default expiration")
+	assert.Equal(t, defaultConfig().defaultTTL, e.TTL, "Loaded item should get default TTL")
+	assert.NotNil(t, e.Loader, "Loader should be kept for refreshing the item")
+}
+
+func TestGetOrLoadDoesNotCacheErrors(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	originErr := errors.New("origin down")
+	key := "TestGetOrLoadDoesNotCacheErrors"
+	_, err := c.GetOrLoad(context.Background(), key, func(ctx context.Context, key string) ([]byte, error) {
+		return nil, originErr
+	})
+	assert.Equal(t, originErr, err)
+	assert.Nil(t, c.GetValue(key), "Failed load should not be cached")
+	value, err := c.GetOrLoad(context.Background(), key, func(ctx context.Context, key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, key, string(value))
+}
+
+func TestGetOrLoadWaiterContext(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	release := make(chan struct{})
+	key := "TestGetOrLoadWaiterContext"
+	go c.GetOrLoad(context.Background(), key, func(ctx context.Context, key string) ([]byte, error) {
+		<-release
+		return []byte(key), nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.GetOrLoad(ctx, key, func(ctx context.Context, key string) ([]byte, error) {
+		t.Error("Waiting caller should not start another load")
+		return nil, nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err, "Waiting caller should give up when its context ends")
+	close(release)
+}
+
+func TestGetOrLoadNotRunning(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	_, err := c.GetOrLoad(context.Background(), "TestGetOrLoadNotRunning", func(ctx context.Context, key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	assert.Equal(t, ErrNotRunning, err)
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	key := "TestGetOrLoadPanic"
+	release := make(chan struct{})
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(context.Background(), key, func(ctx context.Context, key string) ([]byte, error) {
+			<-release
+			panic("boom")
+		})
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		_, err := c.GetOrLoad(context.Background(), key, func(ctx context.Context, key string) ([]byte, error) {
+			return nil, errors.New("waiting caller should not start another load")
+		})
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-waiter:
+			assert.True(t, errors.Is(err, ErrLoaderPanic), "should have got ErrLoaderPanic, got %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Callers should not hang after loader panicked")
+		}
+	}
+	value, err := c.GetOrLoad(context.Background(), key, func(ctx context.Context, key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	assert.NoError(t, err, "Later callers should load again")
+	assert.Equal(t, key, string(value))
+}
+
+func TestGetOrLoadLeaderCancelled(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	key := "TestGetOrLoadLeaderCancelled"
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, key, func(ctx context.Context, key string) ([]byte, error) {
+			select {
+			case <-release:
+				return []byte(key), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+		leader <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	assert.Equal(t, context.Canceled, <-leader, "Caller starting the load should give up when its context ends")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	value, err := c.GetOrLoad(context.Background(), key, func(ctx context.Context, key string) ([]byte, error) {
+		return nil, errors.New("waiting caller should not start another load")
+	})
+	assert.NoError(t, err, "Load should not be cancelled for callers still waiting")
+	assert.Equal(t, key, string(value))
+}
@@ -0,0 +1,23 @@
+package gocachelib
+
+import "log/slog"
+
+// Logger of background events and refresh errors, with structured fields given as alternating
+// keys and values. *slog.Logger implements it. Fields used are key, reason, duration and error,
+// and a few more specific ones.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+var _ Logger = (*slog.Logger)(nil)
+
+// NopLogger discards everything logged
+type NopLogger struct{}
+
+func (NopLogger) Debug(msg string, args ...any) {}
+func (NopLogger) Info(msg string, args ...any)  {}
+func (NopLogger) Warn(msg string, args ...any)  {}
+func (NopLogger) Error(msg string, args ...any) {}
@@ -0,0 +1,96 @@
+package gocachelib
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Metered is a cache whose stats can be exposed as metrics, like Cache and BytesCache
+type Metered interface {
+	Name() string
+	Stats() Stats
+}
+
+// Name of cache given with WithName
+func (c *Cache[K, V]) Name() string {
+	return c.cfg.name
+}
+
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value func(s Stats) float64
+}
+
+var metrics = []metric{
+	{"gocache_hits_total", "counter", "Lookups finding a value.", func(s Stats) float64 { return float64(s.Hits) }},
+	{"gocache_not_found_hits_total", "counter", "Lookups finding a key known to be missing.", func(s Stats) float64 { return float64(s.NotFoundHits) }},
+	{"gocache_misses_total", "counter", "Lookups finding nothing.", func(s Stats) float64 { return float64(s.Misses) }},
+	{"gocache_inserts_total", "counter", "Items set.", func(s Stats) float64 { return float64(s.Inserts) }},
+	{"gocache_revocations_total", "counter", "Items revoked after their TTL.", func(s Stats) float64 { return float64(s.Revocations) }},
+	{"gocache_evictions_total", "counter", "Items removed to make room.", func(s Stats) float64 { return float64(s.Evictions) }},
+	{"gocache_stale_drops_total", "counter", "Items removed on lookup for being staler than their bound.", func(s Stats) float64 { return float64(s.StaleDrops) }},
+	{"gocache_refreshes_total", "counter", "Successful background refreshes.", func(s Stats) float64 { return float64(s.Refreshes) }},
+	{"gocache_refresh_failures_total", "counter", "Failed background refreshes.", func(s Stats) float64 { return float64(s.RefreshFailures) }},
+	{"gocache_dropped_refreshes_total", "counter", "Refreshes dropped because refresh queue was full.", func(s Stats) float64 { return float64(s.DroppedRefreshes) }},
+	{"gocache_dropped_events_total", "counter", "Events not passed to hooks because hooks were too far behind.", func(s Stats) float64 { return float64(s.DroppedEvents) }},
+	{"gocache_entries", "gauge", "Items in cache.", func(s Stats) float64 { return float64(s.Entries) }},
+	{"gocache_bytes", "gauge", "Bytes items take.", func(s Stats) float64 { return float64(s.Bytes) }},
+	{"gocache_queue_depth", "gauge", "Refreshes queued for workers.", func(s Stats) float64 { return float64(s.QueueDepth) }},
+	{"gocache_workers", "gauge", "Workers running.", func(s Stats) float64 { return float64(s.Workers) }},
+	{"gocache_busy_workers", "gauge", "Workers refreshing items.", func(s Stats) float64 { return float64(s.BusyWorkers) }},
+}
+
+// MetricsHandler renders stats of caches in Prometheus text exposition format, each labeled
+// with the name of its cache
+func MetricsHandler(caches ...Metered) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		writeMetrics(bw, caches)
+		bw.Flush()
+	})
+}
+
+func writeMetrics(w *bufio.Writer, caches []Metered) {
+	names := make([]string, len(caches))
+	stats := make([]Stats, len(caches))
+	for i, c := range caches {
+		names[i] = `cache="` + escapeLabel(c.Name()) + `"`
+		stats[i] = c.Stats()
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for i := range caches {
+			fmt.Fprintf(w, "%s{%s} %s\n", m.name, names[i], formatFloat(m.value(stats[i])))
+		}
+	}
+	const histogram = "gocache_load_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Time loader calls of refreshes and GetOrLoad took.\n# TYPE %s histogram\n", histogram, histogram)
+	for i, s := range stats {
+		var cumulative uint64
+		for b, bound := range LoadTimeBuckets {
+			cumulative += s.LoadTimes[b]
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", histogram, names[i], formatFloat(bound.Seconds()), cumulative)
+		}
+		// counted from buckets so that count agrees with them even if a load was recorded meanwhile
+		cumulative += s.LoadTimes[len(LoadTimeBuckets)]
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", histogram, names[i], cumulative)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", histogram, names[i], formatFloat(s.LoadTime.Seconds()))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", histogram, names[i], cumulative)
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
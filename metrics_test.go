@@ -0,0 +1,54 @@
+package gocachelib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	t.Parallel()
+	articles := mustNew(t, WithName("articles"))
+	articles.Start()
+	defer articles.Close(context.Background())
+	pages := mustNew(t, WithName(`pages "v2"`))
+	pages.Start()
+	defer pages.Close(context.Background())
+	articles.AddItem(CacheItem{Key: "key", Value: []byte("value")})
+	articles.GetValue("key")
+	articles.GetValue("other")
+	articles.counters.recordLoad(3 * time.Millisecond)
+	articles.counters.recordLoad(30 * time.Millisecond)
+	articles.counters.recordLoad(1 * time.Minute)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(articles, pages).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE gocache_hits_total counter",
+		`gocache_hits_total{cache="articles"} 1`,
+		`gocache_misses_total{cache="articles"} 1`,
+		`gocache_hits_total{cache="pages \"v2\""} 0`,
+		"# TYPE gocache_entries gauge",
+		`gocache_entries{cache="articles"} 1`,
+		`gocache_workers{cache="articles"} 20`,
+		"# TYPE gocache_load_duration_seconds histogram",
+		`gocache_load_duration_seconds_bucket{cache="articles",le="0.005"} 1`,
+		`gocache_load_duration_seconds_bucket{cache="articles",le="0.025"} 1`,
+		`gocache_load_duration_seconds_bucket{cache="articles",le="0.05"} 2`,
+		`gocache_load_duration_seconds_bucket{cache="articles",le="10"} 2`,
+		`gocache_load_duration_seconds_bucket{cache="articles",le="+Inf"} 3`,
+		`gocache_load_duration_seconds_sum{cache="articles"} 60.033`,
+		`gocache_load_duration_seconds_count{cache="articles"} 3`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.Equal(t, 1, strings.Count(body, "# TYPE gocache_hits_total"), "Metric families should be declared once")
+}
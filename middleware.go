@@ -0,0 +1,225 @@
+package gocachelib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response cached by Middleware
+// Status code of the response
+// Header of the response, without hop-by-hop headers set by the middleware
+// Body of the response
+// Date when the response was rendered
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	Date   time.Time
+}
+
+// ResponseSizer counts header and body sizes of cached responses, for use with WithSizer
+func ResponseSizer(key string, r *Response) int64 {
+	size := int64(len(key) + len(r.Body))
+	for name, values := range r.Header {
+		size += int64(len(name))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	return size
+}
+
+// MiddlewareOption configures response caching middleware
+type MiddlewareOption func(*middlewareConfig)
+
+type middlewareConfig struct {
+	// request headers responses vary by
+	vary []string
+}
+
+// WithVary sets request headers responses vary by, for example Accept-Encoding. Each combination
+// of their values is cached separately.
+func WithVary(headers ...string) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.vary = make([]string, len(headers))
+		for i, h := range headers {
+			c.vary[i] = http.CanonicalHeaderKey(h)
+		}
+	}
+}
+
+// errNotCacheable is returned by loader of a response when handler renders one that must not be cached
+var errNotCacheable = errors.New("response not cacheable")
+
+// Middleware caches full responses of GET and HEAD requests keyed by method, scheme, host, URL and
+// the headers set by WithVary. Expired responses are rendered again in the background by the refresh
+// loop and the stale one is served meanwhile, a failed render keeps it until TTL. Responses are
+// shared between clients, so those with Cache-Control no-store or private, those setting cookies and
+// statuses other than heuristically cacheable ones are not cached. Requests with cookies or
+// Cache-Control no-store are passed through. Requests with Authorization are never served from cache
+// and their responses are cached only if marked public or s-maxage, RFC 9111 section 3.5.
+// Refreshes render again without credentials of the client. X-Cache tells HIT or MISS and Age how
+// many seconds ago a cached response was rendered.
+func Middleware(c *Cache[string, *Response], opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	var cfg middlewareConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || hasDirective(r.Header, "no-store") || r.Header.Get("Cookie") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			authorized := r.Header.Get("Authorization") != ""
+			key := cfg.key(r)
+			if !authorized {
+				if res, ok := c.Get(key); ok {
+					res.write(w, "HIT")
+					return
+				}
+			}
+			res := render(next, r)
+			res.write(w, "MISS")
+			if !res.cacheable() || (authorized && !hasDirective(res.Header, "public") && !hasDirective(res.Header, "s-maxage")) {
+				return
+			}
+			// refreshes render again with a copy of the request detached from the client and its credentials
+			req := r.Clone(context.Background())
+			req.Body = http.NoBody
+			req.Header.Del("Authorization")
+			req.Header.Del("Proxy-Authorization")
+			err := c.Set(Item[string, *Response]{
+				Key:   key,
+				Value: res,
+				Loader: func(ctx context.Context, key string) (*Response, error) {
+					res := render(next, req.WithContext(ctx))
+					if !res.cacheable() {
+						return nil, fmt.Errorf("%w: status %d, Cache-Control %q", errNotCacheable, res.Status, res.Header.Get("Cache-Control"))
+					}
+					return res, nil
+				},
+			})
+			if err != nil && !errors.Is(err, ErrNotRunning) {
+				c.cfg.logger.Warn("Caching response failed", "key", key, "error", err)
+			}
+		})
+	}
+}
+
+// cache key of request
+func (cfg middlewareConfig) key(r *http.Request) string {
+	var b strings.Builder
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	b.WriteString(scheme)
+	b.WriteString("://")
+	b.WriteString(r.Host)
+	b.WriteString(r.URL.RequestURI())
+	for _, name := range cfg.vary {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(name), ", "))
+	}
+	return b.String()
+}
+
+// render response of handler to memory
+func render(h http.Handler, r *http.Request) *Response {
+	w := &responseBuffer{header: http.Header{}}
+	h.ServeHTTP(w, r)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return &Response{Status: w.status, Header: w.header, Body: w.body.Bytes(), Date: time.Now()}
+}
+
+// statuses cacheable by default, RFC 9110 section 15.1
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+	http.StatusPermanentRedirect:    true,
+}
+
+func (res *Response) cacheable() bool {
+	return cacheableStatus[res.Status] &&
+		!hasDirective(res.Header, "no-store") &&
+		!hasDirective(res.Header, "private") &&
+		len(res.Header.Values("Set-Cookie")) == 0 &&
+		res.Header.Get("Vary") != "*"
+}
+
+func (res *Response) write(w http.ResponseWriter, xCache string) {
+	h := w.Header()
+	for name, values := range res.Header {
+		h[name] = append([]string(nil), values...)
+	}
+	h.Set("X-Cache", xCache)
+	if xCache == "HIT" {
+		h.Set("Age", strconv.Itoa(int(time.Since(res.Date).Seconds())))
+	}
+	w.WriteHeader(res.Status)
+	w.Write(res.Body)
+}
+
+// hasDirective tells if Cache-Control header has directive, with or without value
+func hasDirective(h http.Header, directive string) bool {
+	_, ok := directiveValue(h, directive)
+	return ok
+}
+
+// directiveValue returns value of Cache-Control directive, unquoted
+func directiveValue(h http.Header, directive string) (string, bool) {
+	for _, header := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(header, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if strings.EqualFold(name, directive) {
+				return strings.Trim(value, `"`), true
+			}
+		}
+	}
+	return "", false
+}
+
+// responseBuffer is a http.ResponseWriter keeping the response in memory
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseBuffer) Header() http.Header {
+	return w.header
+}
+
+func (w *responseBuffer) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseBuffer) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
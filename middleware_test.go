@@ -0,0 +1,206 @@
+package gocachelib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newResponseCache(t *testing.T, opts ...Option) *Cache[string, *Response] {
+	t.Helper()
+	c, err := New[string, *Response](opts...)
+	if err != nil {
+		t.Fatalf("Should have created cache: %v", err)
+	}
+	assert.NoError(t, c.Start())
+	return c
+}
+
+// handler counting its renders to the body
+func countingHandler(renders *atomic.Int32, header http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := renders.Add(1)
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "%s %d", r.Header.Get("Accept-Language"), n)
+	})
+}
+
+func serve(h http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareCachesResponses(t *testing.T) {
+	t.Parallel()
+	c := newResponseCache(t)
+	defer c.Close(context.Background())
+	var renders atomic.Int32
+	h := Middleware(c)(countingHandler(&renders, nil))
+
+	w := serve(h, http.MethodGet, "/a?page=1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	assert.Equal(t, " 1", w.Body.String())
+
+	w = serve(h, http.MethodGet, "/a?page=1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.Equal(t, "0", w.Header().Get("Age"))
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, " 1", w.Body.String())
+
+	assert.Equal(t, " 2", serve(h, http.MethodGet, "/a?page=2", nil).Body.String())
+	assert.Equal(t, "MISS", serve(h, http.MethodHead, "/a?page=1", nil).Header().Get("X-Cache"))
+	assert.Equal(t, " 4", serve(h, http.MethodPost, "/a?page=1", nil).Body.String())
+	assert.Equal(t, int32(4), renders.Load())
+}
+
+func TestMiddlewareVary(t *testing.T) {
+	t.Parallel()
+	c := newResponseCache(t)
+	defer c.Close(context.Background())
+	var renders atomic.Int32
+	h := Middleware(c, WithVary("accept-language"))(countingHandler(&renders, nil))
+
+	fi := http.Header{"Accept-Language": {"fi"}}
+	en := http.Header{"Accept-Language": {"en"}}
+	assert.Equal(t, "fi 1", serve(h, http.MethodGet, "/", fi).Body.String())
+	assert.Equal(t, "en 2", serve(h, http.MethodGet, "/", en).Body.String())
+	assert.Equal(t, "fi 1", serve(h, http.MethodGet, "/", fi).Body.String())
+	assert.Equal(t, "en 2", serve(h, http.MethodGet, "/", en).Body.String())
+}
+
+func TestMiddlewareHonorsCacheControl(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		request  http.Header
+		response http.Header
+	}{
+		"response no-store": {response: http.Header{"Cache-Control": {"no-store"}}},
+		"response private":  {response: http.Header{"Cache-Control": {"max-age=60, Private"}}},
+		"response vary all": {response: http.Header{"Vary": {"*"}}},
+		"request no-store":  {request: http.Header{"Cache-Control": {"no-store"}}},
+		"response cookie":   {response: http.Header{"Set-Cookie": {"session=alice"}}},
+		"request cookie":    {request: http.Header{"Cookie": {"session=alice"}}},
+		"authorization":     {request: http.Header{"Authorization": {"alice-token"}}},
+	}
+	for name, test := range tests {
+		c := newResponseCache(t)
+		var renders atomic.Int32
+		h := Middleware(c)(countingHandler(&renders, test.response))
+		serve(h, http.MethodGet, "/", test.request)
+		w := serve(h, http.MethodGet, "/", test.request)
+		assert.NotEqual(t, "HIT", w.Header().Get("X-Cache"), name)
+		assert.Equal(t, int32(2), renders.Load(), name)
+		c.Close(context.Background())
+	}
+}
+
+func TestMiddlewareRefreshesInBackground(t *testing.T) {
+	t.Parallel()
+	c := newResponseCache(t, WithDefaultExpiration(50*time.Millisecond), WithLoopInterval(10*time.Millisecond))
+	defer c.Close(context.Background())
+	var renders atomic.Int32
+	h := Middleware(c)(countingHandler(&renders, nil))
+
+	assert.Equal(t, " 1", serve(h, http.MethodGet, "/", nil).Body.String())
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w := serve(h, http.MethodGet, "/", nil)
+		assert.Equal(t, "HIT", w.Header().Get("X-Cache"), "stale response should be served while refreshing")
+		if w.Body.String() != " 1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("response was not refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestResponseSizer(t *testing.T) {
+	t.Parallel()
+	res := &Response{Status: http.StatusOK, Header: http.Header{"Etag": {"abc"}}, Body: []byte("body")}
+	assert.Equal(t, int64(len("key")+len("Etag")+len("abc")+len("body")), ResponseSizer("key", res))
+}
+
+func TestMiddlewareKeysByHost(t *testing.T) {
+	t.Parallel()
+	c := newResponseCache(t)
+	defer c.Close(context.Background())
+	var renders atomic.Int32
+	h := Middleware(c)(countingHandler(&renders, nil))
+
+	assert.Equal(t, " 1", serve(h, http.MethodGet, "http://a.example/x", nil).Body.String())
+	w := serve(h, http.MethodGet, "http://b.example/x", nil)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"), "Responses of other hosts should not be shared")
+	assert.Equal(t, " 2", w.Body.String())
+	assert.Equal(t, " 1", serve(h, http.MethodGet, "http://a.example/x", nil).Body.String())
+}
+
+func TestMiddlewareDoesNotShareCredentials(t *testing.T) {
+	t.Parallel()
+	c := newResponseCache(t)
+	defer c.Close(context.Background())
+	h := Middleware(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Header.Get("Authorization")
+		if cookie, err := r.Cookie("session"); err == nil {
+			user = cookie.Value
+			w.Header().Set("Set-Cookie", "session="+user)
+		}
+		fmt.Fprintf(w, "hello %s", user)
+	}))
+	alice := http.Header{"Authorization": {"alice-token"}}
+	bob := http.Header{"Cookie": {"session=bob"}}
+
+	assert.Equal(t, "hello alice-token", serve(h, http.MethodGet, "/x", alice).Body.String())
+	assert.Equal(t, "hello bob", serve(h, http.MethodGet, "/x", bob).Body.String())
+	w := serve(h, http.MethodGet, "/x", nil)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	assert.Equal(t, "hello ", w.Body.String())
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+	assert.Equal(t, "HIT", serve(h, http.MethodGet, "/x", nil).Header().Get("X-Cache"))
+	w = serve(h, http.MethodGet, "/x", alice)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"), "Requests with credentials should not be served from cache")
+	assert.Equal(t, "hello alice-token", w.Body.String())
+}
+
+func TestMiddlewareCachesPublicAuthorizedResponses(t *testing.T) {
+	t.Parallel()
+	c := newResponseCache(t, WithDefaultExpiration(50*time.Millisecond), WithLoopInterval(10*time.Millisecond))
+	defer c.Close(context.Background())
+	var renders atomic.Int32
+	credentials := make(chan string, 100)
+	h := Middleware(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credentials <- r.Header.Get("Authorization")
+		w.Header().Set("Cache-Control", "public")
+		fmt.Fprintf(w, "%d", renders.Add(1))
+	}))
+
+	assert.Equal(t, "1", serve(h, http.MethodGet, "/x", http.Header{"Authorization": {"alice-token"}}).Body.String())
+	assert.Equal(t, "alice-token", <-credentials)
+	w := serve(h, http.MethodGet, "/x", nil)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"), "Public response should be shared")
+	assert.Equal(t, "1", w.Body.String())
+	select {
+	case user := <-credentials:
+		assert.Empty(t, user, "Refresh should not use credentials of the client")
+	case <-time.After(5 * time.Second):
+		t.Fatal("response was not refreshed")
+	}
+}
@@ -0,0 +1,115 @@
+package gocachelib
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by loaders to tell the key does not exist at the origin, for example
+// on HTTP 404. The key is then remembered as missing for the negative TTL of the cache.
+var ErrNotFound = errors.New("not found")
+
+// LookupStatus tells what cache knows of a key
+type LookupStatus int
+
+const (
+	// LookupMiss key is not cached
+	LookupMiss LookupStatus = iota
+	// LookupHit value of key is cached and fresh
+	LookupHit
+	// LookupNotFound key is known to be missing at the origin
+	LookupNotFound
+	// LookupStale value of key has expired and is served until a refresh replaces it, within its
+	// MaxStale. The refresh may be running, queued or held back, for example by an open breaker.
+	LookupStale
+	// LookupStaleError value of key has expired and refreshing it failed, within its StaleIfError
+	LookupStaleError
+	// LookupExpired value of key is staler than its bound, served with ServeStale policy
+	LookupExpired
+)
+
+func (s LookupStatus) String() string {
+	switch s {
+	case LookupMiss:
+		return "miss"
+	case LookupHit:
+		return "hit"
+	case LookupNotFound:
+		return "not found"
+	case LookupStale:
+		return "stale"
+	case LookupStaleError:
+		return "stale error"
+	case LookupExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// Lookup value from cache, telling apart keys not cached, keys known to be missing, and fresh and
+// stale values. Values staler than their bound are removed with DropStale policy and reported as
+// not cached. Revocation of cached values is postponed, that of missing keys is not.
+func (c *Cache[K, V]) Lookup(key K) (V, LookupStatus) {
+	value, status := c.lookup(key)
+	switch status {
+	case LookupMiss:
+		c.counters.misses.Add(1)
+	case LookupNotFound:
+		c.counters.notFoundHits.Add(1)
+	default:
+		c.counters.hits.Add(1)
+	}
+	return value, status
+}
+
+// lookup without counting it in stats
+func (c *Cache[K, V]) lookup(key K) (V, LookupStatus) {
+	var zero V
+	if c.State() != StateRunning {
+		return zero, LookupMiss
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return zero, LookupMiss
+	}
+	if e.NotFound {
+		return zero, LookupNotFound
+	}
+	status := c.staleness(e, time.Now())
+	if status == LookupExpired && c.cfg.stalePolicy == DropStale {
+		c.cfg.logger.Info("Removing cache item", "key", key, "reason", "stale", "failures", e.Failures, "error", e.LastError)
+		c.remove(e)
+		c.counters.staleDrops.Add(1)
+		c.emit(hookRevoke, eventOf[K, V](key, ReasonStale, e, nil))
+		return zero, LookupMiss
+	}
+	e.UpdateRevokeTime(c.cfg.defaultTTL)
+	e.Hits++
+	c.revokes.fix(e)
+	if c.policy != nil {
+		c.policy.OnAccess(key)
+	}
+	return e.Value, status
+}
+
+// SetNotFound remembers key as missing at the origin for the negative TTL of the cache.
+// ErrNotRunning is returned if cache has not been started or is closed.
+func (c *Cache[K, V]) SetNotFound(key K) error {
+	if c.State() != StateRunning {
+		return ErrNotRunning
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.insert(c.tombstone(key))
+}
+
+// entry of a key known to be missing, revoked after negative TTL and never refreshed
+func (c *Cache[K, V]) tombstone(key K) *entry[K, V] {
+	e := &entry[K, V]{Item: Item[K, V]{Key: key, Expiration: c.cfg.negativeTTL, TTL: c.cfg.negativeTTL}, NotFound: true}
+	now := time.Now()
+	e.ExpireTime = now.Add(c.cfg.negativeTTL)
+	e.RevokeTime = e.ExpireTime
+	return e
+}
@@ -0,0 +1,96 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrLoadRemembersNotFound(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithNegativeTTL(1*time.Minute))
+	c.Start()
+	defer c.Close(context.Background())
+	var loads int32
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		return nil, fmt.Errorf("origin says %w", ErrNotFound)
+	}
+	key := "TestGetOrLoadRemembersNotFound"
+	_, err := c.GetOrLoad(context.Background(), key, loader)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	value, err := c.GetOrLoad(context.Background(), key, loader)
+	assert.Equal(t, ErrNotFound, err, "Missing key should be answered from cache")
+	assert.Nil(t, value)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads), "Loader should not be called again for missing key")
+	_, status := c.Lookup(key)
+	assert.Equal(t, LookupNotFound, status)
+	_, ok := c.Get(key)
+	assert.False(t, ok, "Get should not report missing key as found")
+	info, ok := c.Inspect(key)
+	assert.True(t, ok)
+	assert.True(t, info.NotFound)
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	c.AddItem(CacheItem{Key: "hit", Value: []byte("value")})
+	assert.NoError(t, c.SetNotFound("gone"))
+	value, status := c.Lookup("hit")
+	assert.Equal(t, LookupHit, status)
+	assert.Equal(t, "value", string(value))
+	_, status = c.Lookup("gone")
+	assert.Equal(t, LookupNotFound, status)
+	assert.Nil(t, c.GetValue("gone"))
+	_, status = c.Lookup("nothing")
+	assert.Equal(t, LookupMiss, status)
+	assert.Equal(t, "not found", LookupNotFound.String())
+}
+
+func TestNotFoundExpires(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithNegativeTTL(50*time.Millisecond), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
+	key := "TestNotFoundExpires"
+	assert.NoError(t, c.SetNotFound(key))
+	for i := 0; i < 5; i++ {
+		// lookups of missing keys should not postpone their revocation
+		c.Lookup(key)
+		time.Sleep(20 * time.Millisecond)
+	}
+	_, status := c.Lookup(key)
+	assert.Equal(t, LookupMiss, status, "Missing key should be forgotten after negative TTL")
+}
+
+func TestRefreshNotFoundTurnsItemMissing(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithLoopInterval(10*time.Millisecond), WithRefreshLeadTime(0), WithCircuitBreaker(BreakerPolicy{FailureThreshold: 1, OpenTimeout: 1 * time.Minute, HalfOpenMaxCalls: 1}))
+	c.Start()
+	defer c.Close(context.Background())
+	key := "TestRefreshNotFoundTurnsItemMissing"
+	c.AddItem(CacheItem{Key: key, Value: []byte("old"), Expiration: 20 * time.Millisecond, Group: "origin", GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}})
+	status := LookupHit
+	for i := 0; i < 100 && status != LookupNotFound; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, status = c.Lookup(key)
+	}
+	assert.Equal(t, LookupNotFound, status, "Item should turn missing when refresh finds it gone")
+	assert.Equal(t, BreakerClosed, c.Breakers()["origin"].State, "Missing key should not count as origin failure")
+}
+
+func TestSetNotFoundNotRunning(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	assert.True(t, errors.Is(c.SetNotFound("key"), ErrNotRunning))
+}
@@ -0,0 +1,93 @@
+package gocachelib
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	// ErrNotCached is returned when operating on a key that is not cached
+	ErrNotCached = errors.New("key not cached")
+	// ErrNoLoader is returned when refreshing an item without a loader
+	ErrNoLoader = errors.New("item has no loader")
+	// ErrQueueFull is returned when a refresh could not be queued
+	ErrQueueFull = errors.New("refresh queue full")
+)
+
+// Keys of cached items, including those known to be missing, in no particular order
+func (c *Cache[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]K, 0, len(c.items))
+	for key := range c.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Delete item from cache, false if it was not cached
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if ok {
+		c.remove(e)
+		c.emit(hookRevoke, eventOf[K, V](key, ReasonDeleted, e, nil))
+	}
+	return ok
+}
+
+// Purge removes all items from cache
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.items {
+		c.remove(e)
+		c.emit(hookRevoke, eventOf[K, V](e.Key, ReasonDeleted, e, nil))
+	}
+}
+
+// Refresh queues item for refreshing ahead of others, regardless of its expiration, retry backoff
+// and circuit breaker. Nothing is done if it is being refreshed already. ErrNotCached, ErrNoLoader,
+// ErrQueueFull or ErrNotRunning is returned if it can not be queued.
+func (c *Cache[K, V]) Refresh(key K) error {
+	if c.State() != StateRunning {
+		return ErrNotRunning
+	}
+	c.mu.Lock()
+	e, ok := c.items[key]
+	switch {
+	case !ok || e.NotFound:
+		c.mu.Unlock()
+		return ErrNotCached
+	case e.Loader == nil:
+		c.mu.Unlock()
+		return ErrNoLoader
+	case e.Updating:
+		c.mu.Unlock()
+		return nil
+	}
+	e.Updating = true
+	c.refreshes.remove(e)
+	rank := e.rank(time.Now())
+	rank.priority = math.MaxInt
+	c.mu.Unlock()
+	rejected := c.queue.push(e, rank)
+	if len(rejected) == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for _, r := range rejected {
+		if r == e {
+			err = ErrQueueFull
+		}
+		r.Updating = false
+		if c.items[r.Key] == r {
+			c.schedule(r)
+		}
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package gocachelib
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ScalingPolicy for autoscaling worker pool
+// MinWorkers workers kept running when idle
+// MaxWorkers most workers running at once
+// QueueDepth queued refreshes at which more workers are started, one for each queued refresh
+// QueueWait time the longest queued refresh has waited at which more workers are started
+// IdleTimeout time a worker above MinWorkers waits for refreshes before quitting
+type ScalingPolicy struct {
+	MinWorkers  int
+	MaxWorkers  int
+	QueueDepth  int
+	QueueWait   time.Duration
+	IdleTimeout time.Duration
+}
+
+func (p ScalingPolicy) validate() error {
+	switch {
+	case p.MinWorkers < 0:
+		return fmt.Errorf("%w: min workers must not be negative, got %d", ErrInvalidConfig, p.MinWorkers)
+	case p.MaxWorkers <= 0 || p.MaxWorkers < p.MinWorkers:
+		return fmt.Errorf("%w: max workers must be positive and at least min workers %d, got %d", ErrInvalidConfig, p.MinWorkers, p.MaxWorkers)
+	case p.QueueDepth <= 0:
+		return fmt.Errorf("%w: scaling queue depth must be positive, got %d", ErrInvalidConfig, p.QueueDepth)
+	case p.QueueWait <= 0:
+		return fmt.Errorf("%w: scaling queue wait must be positive, got %v", ErrInvalidConfig, p.QueueWait)
+	case p.IdleTimeout <= 0:
+		return fmt.Errorf("%w: worker idle timeout must be positive, got %v", ErrInvalidConfig, p.IdleTimeout)
+	}
+	return nil
+}
+
+// WithAutoscaling lets worker pool grow from MinWorkers up to MaxWorkers when refreshes queue up,
+// and shrink back when workers are idle. Replaces WithWorkers.
+func WithAutoscaling(p ScalingPolicy) Option {
+	return func(c *config) {
+		c.scaling = &p
+		c.workers = p.MinWorkers
+	}
+}
+
+// start a worker, loopMutex must be held while cache is running so that Close does not wait meanwhile
+func (c *Cache[K, V]) startWorker() {
+	id := atomic.AddInt32(&c.workerIDs, 1)
+	atomic.AddInt32(&c.workerCount, 1)
+	c.workerWg.Add(1)
+	go c.worker(int(id))
+}
+
+// start more workers if refreshes queue up, loopMutex must be held
+func (c *Cache[K, V]) scaleUp() {
+	p := c.cfg.scaling
+	if p == nil {
+		return
+	}
+	depth, wait := c.queue.backlog(time.Now())
+	if depth == 0 || (depth < p.QueueDepth && wait < p.QueueWait) {
+		return
+	}
+	workers := int(atomic.LoadInt32(&c.workerCount))
+	add := minInt(depth, p.MaxWorkers-workers)
+	if add <= 0 {
+		return
+	}
+	c.cfg.logger.Info("Starting more workers", "workers", add, "queued", depth, "duration", wait)
+	for i := 0; i < add; i++ {
+		c.startWorker()
+	}
+}
+
+// whether an idle worker may quit, keeping at least MinWorkers
+func (c *Cache[K, V]) retireWorker() bool {
+	for {
+		workers := atomic.LoadInt32(&c.workerCount)
+		if int(workers) <= c.cfg.scaling.MinWorkers {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&c.workerCount, workers, workers-1) {
+			return true
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package gocachelib
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPoolScalesUpAndDown(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithLoopInterval(5*time.Millisecond), WithRefreshLeadTime(0), WithAutoscaling(ScalingPolicy{
+		MinWorkers:  1,
+		MaxWorkers:  4,
+		QueueDepth:  2,
+		QueueWait:   1 * time.Second,
+		IdleTimeout: 50 * time.Millisecond,
+	}))
+	c.Start()
+	defer c.Close(context.Background())
+	assert.Equal(t, 1, c.Stats().Workers)
+	block := make(chan struct{})
+	getFunc := func(ctx context.Context, key string) ([]byte, error) {
+		<-block
+		return []byte(key), nil
+	}
+	for i := 0; i < 10; i++ {
+		c.AddItem(CacheItem{Key: fmt.Sprint(i), Value: []byte("old"), Expiration: 10 * time.Millisecond, GetFunc: getFunc})
+	}
+	time.Sleep(50 * time.Millisecond)
+	stats := c.Stats()
+	assert.Equal(t, 4, stats.Workers, "Pool should grow up to max workers when refreshes queue up")
+	assert.Equal(t, 4, stats.BusyWorkers)
+	assert.Equal(t, 1.0, stats.Utilization)
+	close(block)
+	// nothing to refresh anymore
+	for i := 0; i < 10; i++ {
+		c.AddItem(CacheItem{Key: fmt.Sprint(i), Value: []byte("new")})
+	}
+	time.Sleep(200 * time.Millisecond)
+	stats = c.Stats()
+	assert.Equal(t, 1, stats.Workers, "Idle workers should quit down to min workers")
+	assert.Equal(t, 0.0, stats.Utilization)
+}
+
+func TestWorkerPoolScalesUpOnQueueWait(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithLoopInterval(5*time.Millisecond), WithRefreshLeadTime(0), WithAutoscaling(ScalingPolicy{
+		MinWorkers:  0,
+		MaxWorkers:  2,
+		QueueDepth:  100,
+		QueueWait:   10 * time.Millisecond,
+		IdleTimeout: 1 * time.Minute,
+	}))
+	c.Start()
+	defer c.Close(context.Background())
+	assert.Equal(t, 0, c.Stats().Workers)
+	refreshed := make(chan struct{}, 1)
+	c.AddItem(CacheItem{Key: "key", Value: []byte("old"), Expiration: 1 * time.Millisecond, GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+		select {
+		case refreshed <- struct{}{}:
+		default:
+		}
+		return []byte("new"), nil
+	}})
+	select {
+	case <-refreshed:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Worker should be started once refresh has waited long enough")
+	}
+	assert.Equal(t, 1, c.Stats().Workers, "One worker should be started for one queued refresh")
+}
@@ -0,0 +1,217 @@
+package gocachelib
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy tells what is done when refresh queue is full
+type OverflowPolicy int
+
+const (
+	// DropNewest drops refreshes queued when queue is full
+	DropNewest OverflowPolicy = iota
+	// DropOldest drops the longest queued refresh to make room
+	DropOldest
+	// Grow queues beyond queue size without limit
+	Grow
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropNewest:
+		return "drop newest"
+	case DropOldest:
+		return "drop oldest"
+	case Grow:
+		return "grow"
+	}
+	return "unknown"
+}
+
+// refreshRank tells how valuable a refresh is. Explicit priority goes first, then weight by
+// recent accesses and staleness.
+type refreshRank struct {
+	priority int
+	weight   float64
+}
+
+func (r refreshRank) before(other refreshRank) bool {
+	if r.priority != other.priority {
+		return r.priority > other.priority
+	}
+	return r.weight > other.weight
+}
+
+// rank of refreshing entry now, mu must be held. Weight grows with recent accesses and with
+// how long ago, relative to its expiration, entry expired.
+func (e *entry[K, V]) rank(now time.Time) refreshRank {
+	staleness := 0.0
+	if e.Expiration > 0 && now.After(e.ExpireTime) {
+		staleness = float64(now.Sub(e.ExpireTime)) / float64(e.Expiration)
+	}
+	return refreshRank{priority: e.Priority, weight: float64(1+e.Hits) * (1 + staleness)}
+}
+
+type queued[K comparable, V any] struct {
+	entry *entry[K, V]
+	rank  refreshRank
+	// order of queueing, earlier ones first among equally ranked
+	seq   uint64
+	index int
+	at    time.Time
+}
+
+// refreshQueue of entries for workers, at most one per key, the most valuable refresh popped
+// first. Pushing never blocks.
+type refreshQueue[K comparable, V any] struct {
+	mu       sync.Mutex
+	nonEmpty *sync.Cond
+	heap     []*queued[K, V]
+	entries  map[K]*queued[K, V]
+	// keys in queueing order for dropping the oldest
+	age      *keyList[K]
+	seq      uint64
+	size     int
+	overflow OverflowPolicy
+	closed   bool
+	// refreshes dropped, shared with cache stats
+	dropped *atomic.Uint64
+}
+
+func newRefreshQueue[K comparable, V any](size int, overflow OverflowPolicy, dropped *atomic.Uint64) *refreshQueue[K, V] {
+	q := &refreshQueue[K, V]{entries: map[K]*queued[K, V]{}, age: newKeyList[K](), size: size, overflow: overflow, dropped: dropped}
+	q.nonEmpty = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *refreshQueue[K, V]) Len() int { return len(q.heap) }
+
+func (q *refreshQueue[K, V]) Less(i, j int) bool {
+	a, b := q.heap[i], q.heap[j]
+	if a.rank != b.rank {
+		return a.rank.before(b.rank)
+	}
+	return a.seq < b.seq
+}
+
+func (q *refreshQueue[K, V]) Swap(i, j int) {
+	q.heap[i], q.heap[j] = q.heap[j], q.heap[i]
+	q.heap[i].index = i
+	q.heap[j].index = j
+}
+
+func (q *refreshQueue[K, V]) Push(x any) {
+	item := x.(*queued[K, V])
+	item.index = len(q.heap)
+	q.heap = append(q.heap, item)
+}
+
+func (q *refreshQueue[K, V]) Pop() any {
+	last := len(q.heap) - 1
+	item := q.heap[last]
+	q.heap[last] = nil
+	q.heap = q.heap[:last]
+	return item
+}
+
+// push entry to queue, returning entries that will not be refreshed: those dropped by overflow
+// policy and those replaced by a newer entry of the same key
+func (q *refreshQueue[K, V]) push(e *entry[K, V], rank refreshRank) []*entry[K, V] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return []*entry[K, V]{e}
+	}
+	if item, ok := q.entries[e.Key]; ok {
+		replaced := item.entry
+		item.entry = e
+		item.rank = rank
+		heap.Fix(q, item.index)
+		return []*entry[K, V]{replaced}
+	}
+	var rejected []*entry[K, V]
+	if len(q.heap) >= q.size {
+		switch q.overflow {
+		case DropNewest:
+			q.dropped.Add(1)
+			return []*entry[K, V]{e}
+		case DropOldest:
+			q.dropped.Add(1)
+			oldest, _ := q.age.popBack()
+			item := q.entries[oldest]
+			heap.Remove(q, item.index)
+			delete(q.entries, oldest)
+			rejected = append(rejected, item.entry)
+		}
+	}
+	q.seq++
+	item := &queued[K, V]{entry: e, rank: rank, seq: q.seq, at: time.Now()}
+	heap.Push(q, item)
+	q.entries[e.Key] = item
+	q.age.pushFront(e.Key)
+	q.nonEmpty.Signal()
+	return rejected
+}
+
+// pop the most valuable queued entry, waiting for one. False is returned once queue is closed.
+// With idle timeout, idle is returned if nothing was queued meanwhile.
+func (q *refreshQueue[K, V]) pop(idleTimeout time.Duration) (e *entry[K, V], ok bool, idle bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var deadline time.Time
+	if idleTimeout > 0 && len(q.heap) == 0 && !q.closed {
+		deadline = time.Now().Add(idleTimeout)
+		timer := time.AfterFunc(idleTimeout, func() {
+			q.mu.Lock()
+			q.nonEmpty.Broadcast()
+			q.mu.Unlock()
+		})
+		defer timer.Stop()
+	}
+	for len(q.heap) == 0 && !q.closed {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return nil, false, true
+		}
+		q.nonEmpty.Wait()
+	}
+	if q.closed {
+		return nil, false, false
+	}
+	item := heap.Pop(q).(*queued[K, V])
+	delete(q.entries, item.entry.Key)
+	q.age.remove(item.entry.Key)
+	return item.entry, true, false
+}
+
+// queued entries and how long the longest queued one has waited
+func (q *refreshQueue[K, V]) backlog(now time.Time) (depth int, wait time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if oldest, ok := q.age.back(); ok {
+		wait = now.Sub(q.entries[oldest].at)
+	}
+	return len(q.heap), wait
+}
+
+// close queue, returning keys still queued in queueing order. Waiting workers are woken up and quit.
+func (q *refreshQueue[K, V]) close() []K {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	keys := q.age.oldestFirst()
+	q.heap = nil
+	q.entries = map[K]*queued[K, V]{}
+	q.age = newKeyList[K]()
+	q.nonEmpty.Broadcast()
+	return keys
+}
+
+// queued entries
+func (q *refreshQueue[K, V]) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.heap)
+}
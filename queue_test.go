@@ -0,0 +1,163 @@
+package gocachelib
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func queueEntry(key string) *entry[string, []byte] {
+	return &entry[string, []byte]{Item: Item[string, []byte]{Key: key}}
+}
+
+func TestRefreshQueueDeduplicates(t *testing.T) {
+	t.Parallel()
+	var dropped atomic.Uint64
+	q := newRefreshQueue[string, []byte](10, DropNewest, &dropped)
+	first, second := queueEntry("a"), queueEntry("a")
+	assert.Empty(t, q.push(first, refreshRank{}))
+	assert.Equal(t, []*entry[string, []byte]{first}, q.push(second, refreshRank{}), "Older entry of the same key should be replaced")
+	assert.Equal(t, 1, q.depth())
+	assert.Equal(t, uint64(0), dropped.Load(), "Replaced entries should not count as dropped")
+	e, ok, _ := q.pop(0)
+	assert.True(t, ok)
+	assert.Equal(t, second, e)
+}
+
+func TestRefreshQueueOverflow(t *testing.T) {
+	t.Parallel()
+	for policy, expected := range map[OverflowPolicy][]string{
+		DropNewest: {"0", "1"},
+		DropOldest: {"1", "2"},
+		Grow:       {"0", "1", "2"},
+	} {
+		var dropped atomic.Uint64
+		q := newRefreshQueue[string, []byte](2, policy, &dropped)
+		for i := 0; i < 3; i++ {
+			q.push(queueEntry(fmt.Sprint(i)), refreshRank{})
+		}
+		assert.Equal(t, len(expected), q.depth(), policy.String())
+		assert.Equal(t, uint64(3-len(expected)), dropped.Load(), policy.String())
+		assert.Equal(t, expected, q.close(), policy.String())
+	}
+}
+
+func TestRefreshQueueCloseWakesWorkers(t *testing.T) {
+	t.Parallel()
+	q := newRefreshQueue[string, []byte](2, DropNewest, new(atomic.Uint64))
+	done := make(chan bool)
+	go func() {
+		_, ok, _ := q.pop(0)
+		done <- ok
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.close()
+	select {
+	case ok := <-done:
+		assert.False(t, ok)
+	case <-time.After(1 * time.Second):
+		t.Fatal("Waiting worker should quit when queue is closed")
+	}
+	assert.Len(t, q.push(queueEntry("a"), refreshRank{}), 1, "Closed queue should reject entries")
+}
+
+func TestFullQueueDoesNotBlockRefreshLoop(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithQueueSize(1), WithLoopInterval(5*time.Millisecond), WithRefreshLeadTime(0))
+	c.Start()
+	block := make(chan struct{})
+	defer func() {
+		close(block)
+		c.Close(context.Background())
+	}()
+	getFunc := func(ctx context.Context, key string) ([]byte, error) {
+		<-block
+		return []byte(key), nil
+	}
+	for i := 0; i < 5; i++ {
+		c.AddItem(CacheItem{Key: fmt.Sprint(i), Value: []byte("old"), Expiration: 10 * time.Millisecond, GetFunc: getFunc})
+	}
+	time.Sleep(50 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		c.revoke()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Revoke loop should not wait for refresh queue")
+	}
+	stats := c.Stats()
+	assert.Equal(t, 1, stats.QueueDepth)
+	assert.True(t, stats.DroppedRefreshes > 0, "Refreshes not fitting in queue should be dropped")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	updating := 0
+	for _, e := range c.items {
+		if e.Updating {
+			updating++
+		}
+	}
+	assert.Equal(t, 2, updating, "Only the refresh in flight and the queued one should be updating")
+}
+
+func TestRefreshQueuePopsMostValuableFirst(t *testing.T) {
+	t.Parallel()
+	q := newRefreshQueue[string, []byte](10, DropNewest, new(atomic.Uint64))
+	now := time.Now()
+	ranked := func(key string, priority int, hits uint64, expiredAgo time.Duration) {
+		e := queueEntry(key)
+		e.Priority = priority
+		e.Hits = hits
+		e.Expiration = 1 * time.Minute
+		e.ExpireTime = now.Add(-expiredAgo)
+		q.push(e, e.rank(now))
+	}
+	ranked("cold", 0, 0, 0)
+	ranked("stale", 0, 0, 2*time.Minute)
+	ranked("hot", 0, 10, 0)
+	ranked("urgent", 1, 0, 0)
+	ranked("cold2", 0, 0, 0)
+	var order []string
+	for i := 0; i < 5; i++ {
+		e, _, _ := q.pop(0)
+		order = append(order, e.Key)
+	}
+	assert.Equal(t, []string{"urgent", "hot", "stale", "cold", "cold2"}, order)
+}
+
+func TestBusyWorkersRefreshHotItemsFirst(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(5*time.Millisecond), WithRefreshLeadTime(0))
+	c.Start()
+	defer c.Close(context.Background())
+	block := make(chan struct{})
+	refreshed := make(chan string, 10)
+	getFunc := func(ctx context.Context, key string) ([]byte, error) {
+		if key == "blocker" {
+			<-block
+		}
+		refreshed <- key
+		return []byte(key), nil
+	}
+	c.AddItem(CacheItem{Key: "blocker", Value: []byte("old"), Expiration: 1 * time.Millisecond, GetFunc: getFunc})
+	time.Sleep(20 * time.Millisecond)
+	c.AddItem(CacheItem{Key: "cold", Value: []byte("old"), Expiration: 30 * time.Millisecond, GetFunc: getFunc})
+	c.AddItem(CacheItem{Key: "hot", Value: []byte("old"), Expiration: 30 * time.Millisecond, GetFunc: getFunc})
+	c.AddItem(CacheItem{Key: "important", Value: []byte("old"), Expiration: 30 * time.Millisecond, GetFunc: getFunc, Priority: 1})
+	for i := 0; i < 20; i++ {
+		c.GetValue("hot")
+	}
+	time.Sleep(60 * time.Millisecond)
+	close(block)
+	var order []string
+	for len(order) < 4 {
+		order = append(order, <-refreshed)
+	}
+	assert.Equal(t, []string{"blocker", "important", "hot", "cold"}, order)
+}
@@ -0,0 +1,60 @@
+package gocachelib
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// RetryPolicy for failed refreshes
+// InitialInterval Time to wait before retrying after the first failure
+// MaxInterval Longest time to wait between retries
+// Multiplier by which the interval grows after each failure in a row
+// Jitter fraction of the interval, between 0 and 1, by which it is randomly shortened or lengthened
+// MaxAttempts failures in a row after which item is no longer refreshed, zero for no limit
+type RetryPolicy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64
+	MaxAttempts     int
+}
+
+func defaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		InitialInterval: 1 * time.Second,
+		MaxInterval:     5 * time.Minute,
+		Multiplier:      2,
+		Jitter:          0.2,
+	}
+}
+
+func (p RetryPolicy) validate() error {
+	switch {
+	case p.InitialInterval <= 0:
+		return fmt.Errorf("%w: retry initial interval must be positive, got %v", ErrInvalidConfig, p.InitialInterval)
+	case p.MaxInterval < p.InitialInterval:
+		return fmt.Errorf("%w: retry max interval %v is less than initial interval %v", ErrInvalidConfig, p.MaxInterval, p.InitialInterval)
+	case p.Multiplier < 1:
+		return fmt.Errorf("%w: retry multiplier must be at least 1, got %v", ErrInvalidConfig, p.Multiplier)
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("%w: retry jitter must be between 0 and 1, got %v", ErrInvalidConfig, p.Jitter)
+	case p.MaxAttempts < 0:
+		return fmt.Errorf("%w: retry max attempts must not be negative, got %d", ErrInvalidConfig, p.MaxAttempts)
+	}
+	return nil
+}
+
+// how long to wait before retrying after given amount of failures in a row
+func (p RetryPolicy) backoff(failures int) time.Duration {
+	interval := float64(p.InitialInterval) * math.Pow(p.Multiplier, float64(failures-1))
+	if interval > float64(p.MaxInterval) {
+		interval = float64(p.MaxInterval)
+	}
+	return min(jitter(time.Duration(interval), p.Jitter), p.MaxInterval)
+}
+
+// whether item should no longer be refreshed after given amount of failures in a row
+func (p RetryPolicy) exhausted(failures int) bool {
+	return p.MaxAttempts > 0 && failures >= p.MaxAttempts
+}
@@ -0,0 +1,193 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{InitialInterval: 1 * time.Second, MaxInterval: 10 * time.Second, Multiplier: 2}
+	assert.Equal(t, 1*time.Second, p.backoff(1))
+	assert.Equal(t, 2*time.Second, p.backoff(2))
+	assert.Equal(t, 8*time.Second, p.backoff(4))
+	assert.Equal(t, 10*time.Second, p.backoff(5), "Backoff should not exceed max interval")
+	assert.Equal(t, 10*time.Second, p.backoff(100))
+}
+
+func TestRetryBackoffJitter(t *testing.T) {
+	p := RetryPolicy{InitialInterval: 1 * time.Second, MaxInterval: 1 * time.Minute, Multiplier: 2, Jitter: 0.5}
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2)
+		assert.True(t, d >= 1*time.Second && d <= 3*time.Second, "Jittered backoff %v out of bounds", d)
+		seen[d] = true
+	}
+	assert.True(t, len(seen) > 1, "Jitter should spread backoffs")
+}
+
+func TestRetryExhausted(t *testing.T) {
+	assert.False(t, RetryPolicy{}.exhausted(1000), "No attempt limit by default")
+	p := RetryPolicy{MaxAttempts: 3}
+	assert.False(t, p.exhausted(2))
+	assert.True(t, p.exhausted(3))
+}
+
+func TestInvalidRetryPolicy(t *testing.T) {
+	valid := defaultRetryPolicy()
+	assert.NoError(t, valid.validate())
+	tests := map[string]func(p *RetryPolicy){
+		"zero initial interval":     func(p *RetryPolicy) { p.InitialInterval = 0 },
+		"max less than initial":     func(p *RetryPolicy) { p.MaxInterval = p.InitialInterval / 2 },
+		"multiplier less than one":  func(p *RetryPolicy) { p.Multiplier = 0.5 },
+		"jitter more than one":      func(p *RetryPolicy) { p.Jitter = 1.5 },
+		"negative max attempts":     func(p *RetryPolicy) { p.MaxAttempts = -1 },
+		"negative jitter":           func(p *RetryPolicy) { p.Jitter = -0.1 },
+		"negative initial interval": func(p *RetryPolicy) { p.InitialInterval = -1 },
+		"zero multiplier":           func(p *RetryPolicy) { p.Multiplier = 0 },
+	}
+	for name, modify := range tests {
+		p := valid
+		modify(&p)
+		assert.True(t, errors.Is(p.validate(), ErrInvalidConfig), name)
+		_, err := New[string, []byte](WithRetryPolicy(p))
+		assert.True(t, errors.Is(err, ErrInvalidConfig), name)
+	}
+}
+
+func TestFailedRefreshBacksOff(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
+	var calls int32
+	key := "TestFailedRefreshBacksOff"
+	c.AddItem(CacheItem{
+		Key:         key,
+		Value:       []byte(key),
+		Expiration:  1 * time.Millisecond,
+		RetryPolicy: &RetryPolicy{InitialInterval: 40 * time.Millisecond, MaxInterval: 1 * time.Second, Multiplier: 2},
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errors.New("origin down")
+		},
+	})
+	time.Sleep(100 * time.Millisecond)
+	// first refresh right away, retries after 40ms and 80ms more
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "Failing item should not be refreshed on every loop")
+	info, _ := c.Inspect(key)
+	assert.Equal(t, 2, info.Failures)
+	assert.True(t, info.RetryTime.After(time.Now()), "Next retry should be visible in entry metadata")
+	assert.False(t, info.GaveUp)
+}
+
+func TestFailedRefreshGivesUpAfterMaxAttempts(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
+	var calls int32
+	key := "TestFailedRefreshGivesUpAfterMaxAttempts"
+	c.AddItem(CacheItem{
+		Key:         key,
+		Value:       []byte(key),
+		Expiration:  1 * time.Millisecond,
+		RetryPolicy: &RetryPolicy{InitialInterval: 1 * time.Millisecond, MaxInterval: 1 * time.Millisecond, Multiplier: 1, MaxAttempts: 3},
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errors.New("origin down")
+		},
+	})
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	info, _ := c.Inspect(key)
+	assert.True(t, info.GaveUp)
+	assert.True(t, info.RetryTime.IsZero())
+	assert.Equal(t, key, string(c.GetValue(key)), "Old value should still be served")
+}
+
+func TestRefreshRecoversAfterGivingUp(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(10*time.Millisecond), WithRefreshLeadTime(0))
+	c.Start()
+	defer c.Close(context.Background())
+	var calls, healthy int32
+	key := "TestRefreshRecoversAfterGivingUp"
+	c.AddItem(CacheItem{
+		Key:         key,
+		Value:       []byte("old"),
+		Expiration:  20 * time.Millisecond,
+		RetryPolicy: &RetryPolicy{InitialInterval: 1 * time.Millisecond, MaxInterval: 1 * time.Millisecond, Multiplier: 1, MaxAttempts: 1},
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			if atomic.LoadInt32(&healthy) == 0 {
+				return nil, errors.New("origin down")
+			}
+			return []byte("new"), nil
+		},
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for info, _ := c.Inspect(key); !info.GaveUp; info, _ = c.Inspect(key) {
+		if time.Now().After(deadline) {
+			t.Fatal("Refreshing should have been given up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	assert.NoError(t, c.Refresh(key))
+	for string(c.GetValue(key)) != "new" {
+		if time.Now().After(deadline) {
+			t.Fatal("Forced refresh should have loaded a new value")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	info, _ := c.Inspect(key)
+	assert.False(t, info.GaveUp, "Successful refresh should resume refreshing")
+	assert.Equal(t, 0, info.Failures)
+	recovered := atomic.LoadInt32(&calls)
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&calls) > recovered, "Item should be refreshed again after expiring")
+}
+
+func TestNoValueRefreshWaitsForExpiration(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithWorkers(1), WithLoopInterval(10*time.Millisecond), WithRefreshLeadTime(0))
+	c.Start()
+	defer c.Close(context.Background())
+	var calls int32
+	key := "TestNoValueRefreshWaitsForExpiration"
+	c.AddItem(CacheItem{
+		Key:        key,
+		Value:      []byte(key),
+		Expiration: 50 * time.Millisecond,
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			return noopGetFunc(ctx, key)
+		},
+	})
+	time.Sleep(300 * time.Millisecond)
+	// one refresh per expiration, about six in 300ms
+	n := atomic.LoadInt32(&calls)
+	assert.True(t, n >= 3 && n <= 8, "Item without new value should be refreshed once per expiration, got %d calls", n)
+	info, _ := c.Inspect(key)
+	assert.True(t, info.ExpireTime.After(time.Now().Add(-30*time.Millisecond)), "Expire time should move forward")
+	assert.Equal(t, 0, info.Failures)
+	assert.Equal(t, key, string(c.GetValue(key)))
+}
+
+func TestSetRejectsInvalidRetryPolicy(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	err := c.AddItem(CacheItem{
+		Key:         "TestSetRejectsInvalidRetryPolicy",
+		RetryPolicy: &RetryPolicy{},
+	})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+}
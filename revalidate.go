@@ -0,0 +1,55 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotModified is returned by loaders to tell the cached value is still current at the origin,
+// for example on HTTP 304. The value is kept and its expire time extended as after a refresh.
+var ErrNotModified = errors.New("not modified")
+
+// LoadState is what a loader knows of the cached value, kept with the item between loads.
+// Loaders get it with LoadStateFrom and update it in place.
+// ETag validator of the cached value
+// LastModified validator of the cached value
+// Expiration of the loaded value given by the origin, for example Cache-Control max-age. Used for items
+// not setting their own expiration instead of cache default, zero if the origin did not tell.
+// Expired the origin told the value is stale already, for example with Cache-Control no-cache or
+// max-age=0. Items not setting their own expiration expire at once and are refreshed on the next loop.
+type LoadState struct {
+	ETag         string
+	LastModified string
+	Expiration   time.Duration
+	Expired      bool
+}
+
+type loadStateKey struct{}
+
+// LoadStateFrom returns the load state of the item being loaded, false outside of loads by cache
+func LoadStateFrom(ctx context.Context) (*LoadState, bool) {
+	state, ok := ctx.Value(loadStateKey{}).(*LoadState)
+	return state, ok
+}
+
+func withLoadState(ctx context.Context, state *LoadState) context.Context {
+	return context.WithValue(ctx, loadStateKey{}, state)
+}
+
+// keep load state with the entry and take expiration from it if the item did not set one
+func (c *Cache[K, V]) setLoadState(e *entry[K, V], state LoadState) {
+	e.loadState = state
+	if !e.originExpiration {
+		return
+	}
+	switch {
+	case state.Expired:
+		// expiration must be positive, the shortest one expires at once
+		e.Expiration = time.Nanosecond
+	case state.Expiration > 0:
+		e.Expiration = state.Expiration
+	default:
+		e.Expiration = c.cfg.defaultExpiration
+	}
+}
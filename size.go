@@ -0,0 +1,131 @@
+package gocachelib
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// ErrItemTooLarge is returned when an item alone would exceed the byte budget of the cache
+var ErrItemTooLarge = errors.New("item exceeds cache size limit")
+
+// Sizer tells how many bytes key and value of an item take. Per entry overhead is added by cache.
+type Sizer[K comparable, V any] func(key K, value V) int64
+
+// WithMaxBytes sets the budget in bytes for keys, values and per entry overhead of cached items,
+// zero for no limit. Least viable items are removed until a new one fits. The budget replaces the
+// default entry limit, WithMaxEntries can still be given to limit both.
+func WithMaxBytes(n int64) Option {
+	return func(c *config) {
+		c.maxBytes = n
+	}
+}
+
+// WithSizer sets how sizes of keys and values are counted against WithMaxBytes. By default strings
+// and byte slices count their length and other types their fixed size, without anything they refer to.
+// Key and value types must match those of the cache.
+func WithSizer[K comparable, V any](f func(key K, value V) int64) Option {
+	return func(c *config) {
+		c.sizer = Sizer[K, V](f)
+	}
+}
+
+func sizerOf[K comparable, V any](cfg config) (Sizer[K, V], error) {
+	if cfg.sizer == nil {
+		return defaultSizer[K, V], nil
+	}
+	sizer, ok := cfg.sizer.(Sizer[K, V])
+	if !ok {
+		return nil, fmt.Errorf("%w: sizer for %T does not match cache of %T", ErrInvalidConfig, cfg.sizer, Sizer[K, V](nil))
+	}
+	return sizer, nil
+}
+
+func defaultSizer[K comparable, V any](key K, value V) int64 {
+	return sizeOf(key) + sizeOf(value)
+}
+
+func sizeOf(v any) int64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	}
+	return int64(reflect.TypeOf(v).Size())
+}
+
+// size of entry counted against the byte budget
+func (c *Cache[K, V]) size(e *entry[K, V]) int64 {
+	return c.sizer(e.Key, e.Value) + int64(unsafe.Sizeof(*e))
+}
+
+// add entry to cache making room for it if needed, replacing the entry of the same key if any.
+// mu must be held.
+func (c *Cache[K, V]) insert(e *entry[K, V]) error {
+	size := c.size(e)
+	if c.cfg.maxBytes > 0 && size > c.cfg.maxBytes {
+		return fmt.Errorf("%w: %v takes %d bytes of %d", ErrItemTooLarge, e.Key, size, c.cfg.maxBytes)
+	}
+	// replaced entry keeps its place until the new one is in, unless it is chosen to make room
+	old := c.items[e.Key]
+	full := func() bool {
+		entries, bytes := len(c.items), c.bytes
+		if old != nil && c.items[e.Key] == old {
+			entries--
+			bytes -= old.Size
+		}
+		return entries >= c.cfg.maxEntries || (c.cfg.maxBytes > 0 && bytes+size > c.cfg.maxBytes)
+	}
+	if full() {
+		c.cfg.logger.Debug("Cache full", "entries", len(c.items), "bytes", c.bytes)
+		for full() && c.revokeLeastViable() {
+		}
+	}
+	if old != nil && c.items[e.Key] == old {
+		c.bytes -= old.Size
+		c.unschedule(old)
+		c.items[e.Key] = e
+		e.Size = size
+		c.bytes += size
+		c.schedule(e)
+		if old != e {
+			c.counters.inserts.Add(1)
+			c.emit(hookInsert, eventOf(e.Key, ReasonReplaced, old, e))
+		}
+		if c.policy != nil {
+			c.policy.OnUpdate(e.Key)
+		}
+		return nil
+	}
+	c.items[e.Key] = e
+	e.Size = size
+	c.bytes += size
+	c.schedule(e)
+	c.counters.inserts.Add(1)
+	c.emit(hookInsert, eventOf[K, V](e.Key, ReasonNew, nil, e))
+	if c.policy != nil {
+		c.policy.OnInsert(e.Key)
+	}
+	return nil
+}
+
+// remove entry from cache, mu must be held
+func (c *Cache[K, V]) remove(e *entry[K, V]) {
+	delete(c.items, e.Key)
+	c.bytes -= e.Size
+	c.unschedule(e)
+	if c.policy != nil {
+		c.policy.OnRemove(e.Key)
+	}
+}
+
+// Bytes tells how many bytes cached items take, as counted against WithMaxBytes
+func (c *Cache[K, V]) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
@@ -0,0 +1,91 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxBytesEvictsUntilItemFits(t *testing.T) {
+	t.Parallel()
+	overhead := int64(unsafe.Sizeof(entry[string, []byte]{}))
+	c := mustNew(t, WithMaxBytes(3*(overhead+101)))
+	c.Start()
+	defer c.Close(context.Background())
+	value := []byte(strings.Repeat("x", 100))
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, c.AddItem(CacheItem{Key: key, Value: value}))
+	}
+	assert.Equal(t, 3*(overhead+101), c.Bytes())
+	assert.NoError(t, c.AddItem(CacheItem{Key: "d", Value: []byte(strings.Repeat("x", 200))}))
+	assert.Equal(t, 2, count(c.Cache), "Two items should have been removed to make room")
+	assert.Nil(t, c.GetValue("a"))
+	assert.Nil(t, c.GetValue("b"))
+	assert.NotNil(t, c.GetValue("d"))
+	assert.Equal(t, 2*overhead+101+201, c.Bytes())
+	info, _ := c.Inspect("d")
+	assert.Equal(t, overhead+201, info.Size)
+}
+
+func TestMaxBytesReplacingItem(t *testing.T) {
+	t.Parallel()
+	overhead := int64(unsafe.Sizeof(entry[string, []byte]{}))
+	c := mustNew(t, WithMaxBytes(2*(overhead+11)))
+	c.Start()
+	defer c.Close(context.Background())
+	c.AddItem(CacheItem{Key: "a", Value: []byte("0123456789")})
+	c.AddItem(CacheItem{Key: "b", Value: []byte("0123456789")})
+	assert.NoError(t, c.AddItem(CacheItem{Key: "a", Value: []byte("x")}))
+	assert.Equal(t, 2, count(c.Cache), "Replaced item should not count twice")
+	assert.Equal(t, 2*overhead+2+11, c.Bytes())
+}
+
+func TestMaxBytesReplacesDefaultEntryLimit(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithMaxBytes(1<<20))
+	c.Start()
+	defer c.Close(context.Background())
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, c.AddItem(CacheItem{Key: strconv.Itoa(i), Value: []byte("value")}))
+	}
+	assert.Equal(t, 100, count(c.Cache), "Byte budget should not be limited by default entry limit")
+
+	limited := mustNew(t, WithMaxBytes(1<<20), WithMaxEntries(10))
+	limited.Start()
+	defer limited.Close(context.Background())
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, limited.AddItem(CacheItem{Key: strconv.Itoa(i), Value: []byte("value")}))
+	}
+	assert.Equal(t, 10, count(limited.Cache), "Explicit entry limit should apply with byte budget")
+}
+
+func TestItemLargerThanBudgetIsRejected(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithMaxBytes(1024))
+	c.Start()
+	defer c.Close(context.Background())
+	c.AddItem(CacheItem{Key: "small", Value: []byte("value")})
+	err := c.AddItem(CacheItem{Key: "large", Value: make([]byte, 2048)})
+	assert.True(t, errors.Is(err, ErrItemTooLarge))
+	assert.Nil(t, c.GetValue("large"))
+	assert.NotNil(t, c.GetValue("small"), "Rejected item should not evict others")
+}
+
+func TestWithSizer(t *testing.T) {
+	t.Parallel()
+	type article struct{ body string }
+	c, err := New[int, article](WithSizer(func(id int, a article) int64 { return int64(len(a.body)) }))
+	assert.NoError(t, err)
+	c.Start()
+	defer c.Close(context.Background())
+	c.Set(Item[int, article]{Key: 1, Value: article{body: "hello"}})
+	assert.Equal(t, 5+int64(unsafe.Sizeof(entry[int, article]{})), c.Bytes())
+
+	_, err = New[string, []byte](WithSizer(func(id int, a article) int64 { return 0 }))
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Sizer of other types should be rejected")
+}
@@ -0,0 +1,70 @@
+package gocachelib
+
+import (
+	"time"
+)
+
+// StalePolicy tells what is done with values staler than their bound
+type StalePolicy int
+
+const (
+	// ServeStale serves values staler than their bound, reported with LookupExpired
+	ServeStale StalePolicy = iota
+	// DropStale removes values staler than their bound when they are looked up
+	DropStale
+)
+
+func (p StalePolicy) String() string {
+	switch p {
+	case ServeStale:
+		return "serve stale"
+	case DropStale:
+		return "drop stale"
+	}
+	return "unknown"
+}
+
+// WithMaxStale sets how long after expiring values of items not defining their own bound are
+// served while being refreshed, zero for no limit
+func WithMaxStale(d time.Duration) Option {
+	return func(c *config) {
+		c.maxStale = d
+	}
+}
+
+// WithStaleIfError sets how long after expiring values of items not defining their own bound are
+// served while their refreshes fail, zero for no limit
+func WithStaleIfError(d time.Duration) Option {
+	return func(c *config) {
+		c.staleIfError = d
+	}
+}
+
+// WithStalePolicy sets what is done with values staler than their bound, default ServeStale
+func WithStalePolicy(p StalePolicy) Option {
+	return func(c *config) {
+		c.stalePolicy = p
+	}
+}
+
+// freshness of cached value by how long ago it expired. Values of items without loader are never
+// refreshed and so never stale.
+func (c *Cache[K, V]) staleness(e *entry[K, V], now time.Time) LookupStatus {
+	if e.Loader == nil || now.Before(e.ExpireTime) {
+		return LookupHit
+	}
+	status, bound := LookupStale, e.MaxStale
+	if bound == 0 {
+		bound = c.cfg.maxStale
+	}
+	if e.Failures > 0 {
+		status, bound = LookupStaleError, e.StaleIfError
+		if bound == 0 {
+			bound = c.cfg.staleIfError
+		}
+	}
+	if bound > 0 && now.Sub(e.ExpireTime) > bound {
+		return LookupExpired
+	}
+	return status
+}
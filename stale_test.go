@@ -0,0 +1,107 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func noopLoader(ctx context.Context, key string) ([]byte, error) {
+	return []byte("new"), nil
+}
+
+func failingLoader(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("origin down")
+}
+
+// wait until refreshing key has failed
+func waitFailure(t *testing.T, c *BytesCache, key string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if info, _ := c.Inspect(key); info.Failures > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("refreshing %s should have failed", key)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLookupStale(t *testing.T) {
+	t.Parallel()
+	// refresh loop does not run during the test
+	c := mustNew(t, WithLoopInterval(1*time.Minute), WithMaxStale(20*time.Millisecond))
+	c.Start()
+	defer c.Close(context.Background())
+	c.Set(Item[string, []byte]{Key: "fresh", Value: []byte("old"), Expiration: 1 * time.Minute, Loader: noopLoader})
+	c.Set(Item[string, []byte]{Key: "stale", Value: []byte("old"), Expiration: 1 * time.Millisecond, MaxStale: 1 * time.Minute, Loader: noopLoader})
+	c.Set(Item[string, []byte]{Key: "expired", Value: []byte("old"), Expiration: 1 * time.Millisecond, Loader: noopLoader})
+	c.Set(Item[string, []byte]{Key: "no loader", Value: []byte("old"), Expiration: 1 * time.Millisecond})
+	time.Sleep(50 * time.Millisecond)
+
+	for key, want := range map[string]LookupStatus{
+		"fresh":     LookupHit,
+		"stale":     LookupStale,
+		"expired":   LookupExpired,
+		"no loader": LookupHit,
+	} {
+		value, status := c.Lookup(key)
+		assert.Equal(t, want, status, key)
+		assert.Equal(t, "old", string(value), key)
+	}
+	value, ok := c.Get("expired")
+	assert.True(t, ok, "values staler than their bound should be served by default")
+	assert.Equal(t, "old", string(value))
+	assert.Equal(t, uint64(5), c.Stats().Hits)
+}
+
+func TestLookupStaleError(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithLoopInterval(5*time.Millisecond), WithRefreshLeadTime(0), WithRetryPolicy(RetryPolicy{InitialInterval: 1 * time.Minute, MaxInterval: 1 * time.Minute, Multiplier: 1}))
+	c.Start()
+	defer c.Close(context.Background())
+	c.Set(Item[string, []byte]{Key: "a", Value: []byte("old"), Expiration: 10 * time.Millisecond, MaxStale: 1 * time.Millisecond, StaleIfError: 1 * time.Minute, Loader: failingLoader})
+	c.Set(Item[string, []byte]{Key: "b", Value: []byte("old"), Expiration: 10 * time.Millisecond, MaxStale: 1 * time.Minute, StaleIfError: 1 * time.Millisecond, Loader: failingLoader})
+	waitFailure(t, c, "a")
+	waitFailure(t, c, "b")
+	time.Sleep(10 * time.Millisecond)
+
+	value, status := c.Lookup("a")
+	assert.Equal(t, LookupStaleError, status)
+	assert.Equal(t, "old", string(value))
+	_, status = c.Lookup("b")
+	assert.Equal(t, LookupExpired, status)
+}
+
+func TestDropStale(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithLoopInterval(1*time.Minute), WithStalePolicy(DropStale))
+	c.Start()
+	defer c.Close(context.Background())
+	r := record(c)
+	c.Set(Item[string, []byte]{Key: "a", Value: []byte("old"), Expiration: 1 * time.Millisecond, MaxStale: 1 * time.Millisecond, Loader: noopLoader})
+	c.Set(Item[string, []byte]{Key: "b", Value: []byte("old"), Expiration: 1 * time.Millisecond, MaxStale: 1 * time.Minute, Loader: noopLoader})
+	time.Sleep(20 * time.Millisecond)
+
+	_, status := c.Lookup("a")
+	assert.Equal(t, LookupMiss, status)
+	_, ok := c.Inspect("a")
+	assert.False(t, ok, "stale item should have been removed")
+	revokes := r.wait(t, "revoke", 1)
+	assert.Len(t, revokes, 1)
+	assert.Equal(t, ReasonStale, revokes[0].Reason)
+	assert.Equal(t, "old", string(revokes[0].OldValue))
+
+	_, status = c.Lookup("b")
+	assert.Equal(t, LookupStale, status)
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.StaleDrops)
+	assert.Equal(t, uint64(0), stats.Revocations, "Stale drops should not count as revocations after TTL")
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Hits)
+}
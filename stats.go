@@ -0,0 +1,187 @@
+package gocachelib
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats of a cache. Counters grow from the start of cache or the last ResetStats, the rest
+// tell the current state.
+// Hits lookups finding a value
+// NotFoundHits lookups finding a key known to be missing
+// Misses lookups finding nothing
+// Inserts items set, including replaced ones and keys known to be missing
+// Revocations items revoked after their TTL
+// Evictions items removed to make room
+// StaleDrops items removed on lookup for being staler than their bound, with DropStale policy
+// Refreshes successful background refreshes
+// RefreshFailures failed background refreshes
+// DroppedRefreshes refreshes dropped because refresh queue was full
+// DroppedEvents events not passed to hooks because hooks were WithHookBuffer events behind
+// Loads loader calls of refreshes and GetOrLoad
+// LoadTime total time loader calls took
+// LoadTimes loader calls by how long they took, counted in the first of LoadTimeBuckets they fit in
+// and in the last one if they fit in none
+// Entries items in cache
+// Bytes items take, as counted against WithMaxBytes
+// QueueDepth refreshes queued for workers
+// Workers running
+// BusyWorkers workers refreshing items
+// Utilization share of workers refreshing items, between 0 and 1
+type Stats struct {
+	Hits             uint64
+	NotFoundHits     uint64
+	Misses           uint64
+	Inserts          uint64
+	Revocations      uint64
+	Evictions        uint64
+	StaleDrops       uint64
+	Refreshes        uint64
+	RefreshFailures  uint64
+	DroppedRefreshes uint64
+	DroppedEvents    uint64
+	Loads            uint64
+	LoadTime         time.Duration
+	LoadTimes        [len(LoadTimeBuckets) + 1]uint64
+
+	Entries     int
+	Bytes       int64
+	QueueDepth  int
+	Workers     int
+	BusyWorkers int
+	Utilization float64
+}
+
+// LoadTimeBuckets are upper bounds of loader call durations counted in Stats.LoadTimes
+var LoadTimeBuckets = [...]time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// HitRatio share of lookups finding a value or a key known to be missing, zero without lookups
+func (s Stats) HitRatio() float64 {
+	lookups := s.Hits + s.NotFoundHits + s.Misses
+	if lookups == 0 {
+		return 0
+	}
+	return float64(s.Hits+s.NotFoundHits) / float64(lookups)
+}
+
+// AverageLoadTime of loader calls, zero without loads
+func (s Stats) AverageLoadTime() time.Duration {
+	if s.Loads == 0 {
+		return 0
+	}
+	return s.LoadTime / time.Duration(s.Loads)
+}
+
+// Sub returns counters grown since an earlier snapshot, with the current state of s
+func (s Stats) Sub(earlier Stats) Stats {
+	s.Hits -= earlier.Hits
+	s.NotFoundHits -= earlier.NotFoundHits
+	s.Misses -= earlier.Misses
+	s.Inserts -= earlier.Inserts
+	s.Revocations -= earlier.Revocations
+	s.Evictions -= earlier.Evictions
+	s.StaleDrops -= earlier.StaleDrops
+	s.Refreshes -= earlier.Refreshes
+	s.RefreshFailures -= earlier.RefreshFailures
+	s.DroppedRefreshes -= earlier.DroppedRefreshes
+	s.DroppedEvents -= earlier.DroppedEvents
+	s.Loads -= earlier.Loads
+	s.LoadTime -= earlier.LoadTime
+	for i := range s.LoadTimes {
+		s.LoadTimes[i] -= earlier.LoadTimes[i]
+	}
+	return s
+}
+
+// counters of Stats, updated atomically without locks
+type counters struct {
+	hits             atomic.Uint64
+	notFoundHits     atomic.Uint64
+	misses           atomic.Uint64
+	inserts          atomic.Uint64
+	revocations      atomic.Uint64
+	evictions        atomic.Uint64
+	staleDrops       atomic.Uint64
+	refreshes        atomic.Uint64
+	refreshFailures  atomic.Uint64
+	droppedRefreshes atomic.Uint64
+	droppedEvents    atomic.Uint64
+	loads            atomic.Uint64
+	loadNanos        atomic.Int64
+	loadTimes        [len(LoadTimeBuckets) + 1]atomic.Uint64
+}
+
+func (c *counters) recordLoad(took time.Duration) {
+	c.loads.Add(1)
+	c.loadNanos.Add(int64(took))
+	bucket := 0
+	for bucket < len(LoadTimeBuckets) && took > LoadTimeBuckets[bucket] {
+		bucket++
+	}
+	c.loadTimes[bucket].Add(1)
+}
+
+// Stats returns a snapshot of cache stats
+func (c *Cache[K, V]) Stats() Stats {
+	s := Stats{
+		Hits:             c.counters.hits.Load(),
+		NotFoundHits:     c.counters.notFoundHits.Load(),
+		Misses:           c.counters.misses.Load(),
+		Inserts:          c.counters.inserts.Load(),
+		Revocations:      c.counters.revocations.Load(),
+		Evictions:        c.counters.evictions.Load(),
+		StaleDrops:       c.counters.staleDrops.Load(),
+		Refreshes:        c.counters.refreshes.Load(),
+		RefreshFailures:  c.counters.refreshFailures.Load(),
+		DroppedRefreshes: c.counters.droppedRefreshes.Load(),
+		DroppedEvents:    c.counters.droppedEvents.Load(),
+		Loads:            c.counters.loads.Load(),
+		LoadTime:         time.Duration(c.counters.loadNanos.Load()),
+		QueueDepth:       c.queue.depth(),
+		Workers:          int(atomic.LoadInt32(&c.workerCount)),
+		BusyWorkers:      int(atomic.LoadInt32(&c.busyWorkers)),
+	}
+	for i := range s.LoadTimes {
+		s.LoadTimes[i] = c.counters.loadTimes[i].Load()
+	}
+	c.mu.Lock()
+	s.Entries = len(c.items)
+	s.Bytes = c.bytes
+	c.mu.Unlock()
+	if s.Workers > 0 {
+		s.Utilization = float64(s.BusyWorkers) / float64(s.Workers)
+	}
+	return s
+}
+
+// ResetStats zeroes counters of stats
+func (c *Cache[K, V]) ResetStats() {
+	c.counters.hits.Store(0)
+	c.counters.notFoundHits.Store(0)
+	c.counters.misses.Store(0)
+	c.counters.inserts.Store(0)
+	c.counters.revocations.Store(0)
+	c.counters.evictions.Store(0)
+	c.counters.staleDrops.Store(0)
+	c.counters.refreshes.Store(0)
+	c.counters.refreshFailures.Store(0)
+	c.counters.droppedRefreshes.Store(0)
+	c.counters.droppedEvents.Store(0)
+	c.counters.loads.Store(0)
+	c.counters.loadNanos.Store(0)
+	for i := range c.counters.loadTimes {
+		c.counters.loadTimes[i].Store(0)
+	}
+}
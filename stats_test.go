@@ -0,0 +1,77 @@
+package gocachelib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsCounters(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t, WithMaxEntries(3), WithNegativeTTL(20*time.Millisecond), WithLoopInterval(5*time.Millisecond), WithRefreshLeadTime(0), WithRetryPolicy(fastRetry))
+	c.Start()
+	defer c.Close(context.Background())
+	var mu sync.Mutex
+	fail := true
+	c.AddItem(CacheItem{Key: "b", Value: []byte("b"), Expiration: 10 * time.Millisecond, GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			fail = false
+			return nil, errors.New("origin down")
+		}
+		return []byte("new"), nil
+	}})
+	c.AddItem(CacheItem{Key: "c", Value: []byte("c")})
+	c.GetValue("b")
+	c.GetValue("c")
+	c.GetValue("a")
+	c.GetOrLoad(context.Background(), "a", func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	})
+	c.GetValue("a")
+	time.Sleep(100 * time.Millisecond)
+	c.AddItem(CacheItem{Key: "d", Value: []byte("d")})
+	c.AddItem(CacheItem{Key: "e", Value: []byte("e")})
+	s := c.Stats()
+	assert.Equal(t, uint64(2), s.Hits)
+	assert.Equal(t, uint64(1), s.NotFoundHits)
+	assert.Equal(t, uint64(2), s.Misses)
+	assert.Equal(t, uint64(5), s.Inserts)
+	assert.Equal(t, uint64(1), s.Revocations, "Missing key should be revoked after negative TTL")
+	assert.Equal(t, uint64(1), s.Evictions, "Adding e should make room")
+	assert.Equal(t, uint64(1), s.RefreshFailures)
+	assert.True(t, s.Refreshes >= 1, "Refreshes should be counted, got %d", s.Refreshes)
+	// a refresh may be finishing, its load is counted first
+	assert.True(t, s.Loads >= s.Refreshes+s.RefreshFailures+1, "Refreshes and GetOrLoad should count as loads")
+	assert.True(t, s.AverageLoadTime() > 0)
+	assert.Equal(t, 3, s.Entries)
+	assert.InDelta(t, 0.6, s.HitRatio(), 0.001)
+}
+
+func TestStatsResetAndSub(t *testing.T) {
+	t.Parallel()
+	c := mustNew(t)
+	c.Start()
+	defer c.Close(context.Background())
+	c.AddItem(CacheItem{Key: "key", Value: []byte("value")})
+	c.GetValue("key")
+	before := c.Stats()
+	c.GetValue("key")
+	c.GetValue("other")
+	delta := c.Stats().Sub(before)
+	assert.Equal(t, uint64(1), delta.Hits)
+	assert.Equal(t, uint64(1), delta.Misses)
+	assert.Equal(t, uint64(0), delta.Inserts)
+	assert.Equal(t, 1, delta.Entries, "State should not be subtracted")
+	c.ResetStats()
+	s := c.Stats()
+	assert.Equal(t, uint64(0), s.Hits)
+	assert.Equal(t, uint64(0), s.Misses)
+	assert.Equal(t, 1, s.Entries)
+	assert.Equal(t, time.Duration(0), s.AverageLoadTime())
+}
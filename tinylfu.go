@@ -0,0 +1,162 @@
+package gocachelib
+
+import (
+	"fmt"
+	"hash/maphash"
+)
+
+// TinyLFU is a W-TinyLFU eviction policy. New items enter a small LRU window, and when it is full
+// the window's least recent item competes with the main area's victim, the one less frequently
+// used according to a count-min sketch being evicted. The main area is a segmented LRU whose
+// protected segment keeps items used more than once.
+type TinyLFU[K comparable] struct {
+	window    *keyList[K]
+	probation *keyList[K]
+	protected *keyList[K]
+
+	windowSize    int
+	protectedSize int
+
+	sketch *sketch
+}
+
+// NewTinyLFU creates a W-TinyLFU eviction policy for a cache of about capacity items,
+// with 1% of them in the window and 80% of the rest protected
+func NewTinyLFU[K comparable](capacity int) *TinyLFU[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	windowSize := capacity / 100
+	if windowSize < 1 {
+		windowSize = 1
+	}
+	return &TinyLFU[K]{
+		window:        newKeyList[K](),
+		probation:     newKeyList[K](),
+		protected:     newKeyList[K](),
+		windowSize:    windowSize,
+		protectedSize: (capacity - windowSize) * 8 / 10,
+		sketch:        newSketch(capacity),
+	}
+}
+
+func (p *TinyLFU[K]) OnInsert(key K) {
+	p.sketch.increment(hashKey(p.sketch.seed, key))
+	p.OnRemove(key)
+	p.window.pushFront(key)
+	// window's least recent item survived the competition for room, move it to main area
+	for p.window.len() > p.windowSize {
+		moved, _ := p.window.popBack()
+		p.probation.pushFront(moved)
+	}
+}
+
+func (p *TinyLFU[K]) OnAccess(key K) {
+	p.sketch.increment(hashKey(p.sketch.seed, key))
+	switch {
+	case p.window.touch(key):
+	case p.protected.touch(key):
+	case p.probation.remove(key):
+		p.protected.pushFront(key)
+		for p.protected.len() > p.protectedSize {
+			demoted, _ := p.protected.popBack()
+			p.probation.pushFront(demoted)
+		}
+	}
+}
+
+func (p *TinyLFU[K]) OnUpdate(key K) { p.OnAccess(key) }
+
+func (p *TinyLFU[K]) OnRemove(key K) {
+	_ = p.window.remove(key) || p.probation.remove(key) || p.protected.remove(key)
+}
+
+func (p *TinyLFU[K]) Victim() (K, bool) {
+	main, ok := p.probation.back()
+	if !ok {
+		main, ok = p.protected.back()
+	}
+	candidate, full := p.window.back()
+	if !ok {
+		return candidate, full
+	}
+	if full && p.window.len() >= p.windowSize {
+		// admit candidate to main area only if it is used more often than main's victim
+		if p.sketch.estimate(hashKey(p.sketch.seed, candidate)) <= p.sketch.estimate(hashKey(p.sketch.seed, main)) {
+			return candidate, true
+		}
+	}
+	return main, true
+}
+
+// count-min sketch of 4 rows of 8 bit counters, all halved after 10 times its capacity increments
+type sketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+	seed      maphash.Seed
+}
+
+func newSketch(capacity int) *sketch {
+	// wide enough for few collisions among the items cached
+	width := 16
+	for width < 8*capacity {
+		width *= 2
+	}
+	s := &sketch{mask: uint64(width - 1), resetAt: 10 * capacity, seed: maphash.MakeSeed()}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *sketch) increment(h uint64) {
+	for i := range s.rows {
+		c := &s.rows[i][s.index(h, i)]
+		if *c < 255 {
+			*c++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		// age counts so that frequency reflects recent use
+		for i := range s.rows {
+			for j := range s.rows[i] {
+				s.rows[i][j] /= 2
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *sketch) estimate(h uint64) uint8 {
+	least := uint8(255)
+	for i := range s.rows {
+		if c := s.rows[i][s.index(h, i)]; c < least {
+			least = c
+		}
+	}
+	return least
+}
+
+// counter of row i for hash h. Each row mixes the hash with its own offset through the splitmix64
+// finalizer, so that keys sharing a counter in one row rarely share those of the others.
+func (s *sketch) index(h uint64, i int) uint64 {
+	h += uint64(i+1) * 0x9e3779b97f4a7c15
+	h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+	h = (h ^ h>>27) * 0x94d049bb133111eb
+	return (h ^ h>>31) & s.mask
+}
+
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+	switch k := any(key).(type) {
+	case string:
+		h.WriteString(k)
+	default:
+		fmt.Fprint(&h, k)
+	}
+	return h.Sum64()
+}
@@ -1,17 +1,30 @@
 package gocachelib
 
 import (
+	"math"
+	"math/rand"
+	"sync"
 	"time"
 )
 
-func doEvery(d time.Duration, f func()) *time.Ticker {
+// random source for jitter, seeded separately so that processes do not share the sequence
+var random = rand.New(rand.NewSource(time.Now().UnixNano()))
+var randomMutex = sync.Mutex{}
+
+// call f every d until done is closed
+func doEvery(d time.Duration, done <-chan struct{}, f func()) {
 	ticker := time.NewTicker(d)
 	go func() {
-		for range ticker.C {
-			f()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				f()
+			case <-done:
+				return
+			}
 		}
 	}()
-	return ticker
 }
 
 func max(d1 time.Duration, d2 time.Duration) time.Duration {
@@ -20,3 +33,44 @@ func max(d1 time.Duration, d2 time.Duration) time.Duration {
 	}
 	return d2
 }
+
+func min(d1 time.Duration, d2 time.Duration) time.Duration {
+	if d1 < d2 {
+		return d1
+	}
+	return d2
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// randomly shorten or lengthen d by up to given fraction of it
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction == 0 {
+		return d
+	}
+	randomMutex.Lock()
+	r := random.Float64()
+	randomMutex.Unlock()
+	return time.Duration(float64(d) * (1 + fraction*(2*r-1)))
+}
+
+// exponentially distributed random value with mean 1, how far ahead XFetch refreshes in units of load time
+func expRandom() float64 {
+	randomMutex.Lock()
+	r := random.Float64()
+	randomMutex.Unlock()
+	// r is in [0, 1), 1-r is never zero
+	return -math.Log(1 - r)
+}